@@ -0,0 +1,199 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// Declension - unit name in the genitive, dative, instrumental and prepositional cases.
+// Singular forms follow numbers ending with 1 but 11: "двадцати одного рубля", plural
+// forms follow the others: "двадцати двух рублей"
+type Declension struct {
+	Singular [4]string
+	Plural   [4]string
+}
+
+// caseWords - words of numbers in a grammatical case
+type caseWords struct {
+	zero     string
+	ones     [10]string
+	onesFem  [10]string
+	teens    [10]string
+	tens     [10]string
+	hundreds [10]string
+}
+
+// Russian words of numbers in the oblique cases
+var russianCases = map[GrammaticalCase]caseWords{
+	Genitive: {
+		zero:     "нуля",
+		ones:     [10]string{"", "одного", "двух", "трех", "четырех", "пяти", "шести", "семи", "восьми", "девяти"},
+		onesFem:  [10]string{"", "одной", "двух", "трех", "четырех", "пяти", "шести", "семи", "восьми", "девяти"},
+		teens:    [10]string{"десяти", "одиннадцати", "двенадцати", "тринадцати", "четырнадцати", "пятнадцати", "шестнадцати", "семнадцати", "восемнадцати", "девятнадцати"},
+		tens:     [10]string{"", "", "двадцати", "тридцати", "сорока", "пятидесяти", "шестидесяти", "семидесяти", "восьмидесяти", "девяноста"},
+		hundreds: [10]string{"", "ста", "двухсот", "трехсот", "четырехсот", "пятисот", "шестисот", "семисот", "восьмисот", "девятисот"},
+	},
+	Dative: {
+		zero:     "нулю",
+		ones:     [10]string{"", "одному", "двум", "трем", "четырем", "пяти", "шести", "семи", "восьми", "девяти"},
+		onesFem:  [10]string{"", "одной", "двум", "трем", "четырем", "пяти", "шести", "семи", "восьми", "девяти"},
+		teens:    [10]string{"десяти", "одиннадцати", "двенадцати", "тринадцати", "четырнадцати", "пятнадцати", "шестнадцати", "семнадцати", "восемнадцати", "девятнадцати"},
+		tens:     [10]string{"", "", "двадцати", "тридцати", "сорока", "пятидесяти", "шестидесяти", "семидесяти", "восьмидесяти", "девяноста"},
+		hundreds: [10]string{"", "ста", "двумстам", "тремстам", "четыремстам", "пятистам", "шестистам", "семистам", "восьмистам", "девятистам"},
+	},
+	Instrumental: {
+		zero:     "нулем",
+		ones:     [10]string{"", "одним", "двумя", "тремя", "четырьмя", "пятью", "шестью", "семью", "восемью", "девятью"},
+		onesFem:  [10]string{"", "одной", "двумя", "тремя", "четырьмя", "пятью", "шестью", "семью", "восемью", "девятью"},
+		teens:    [10]string{"десятью", "одиннадцатью", "двенадцатью", "тринадцатью", "четырнадцатью", "пятнадцатью", "шестнадцатью", "семнадцатью", "восемнадцатью", "девятнадцатью"},
+		tens:     [10]string{"", "", "двадцатью", "тридцатью", "сорока", "пятьюдесятью", "шестьюдесятью", "семьюдесятью", "восемьюдесятью", "девяноста"},
+		hundreds: [10]string{"", "ста", "двумястами", "тремястами", "четырьмястами", "пятьюстами", "шестьюстами", "семьюстами", "восемьюстами", "девятьюстами"},
+	},
+	Prepositional: {
+		zero:     "нуле",
+		ones:     [10]string{"", "одном", "двух", "трех", "четырех", "пяти", "шести", "семи", "восьми", "девяти"},
+		onesFem:  [10]string{"", "одной", "двух", "трех", "четырех", "пяти", "шести", "семи", "восьми", "девяти"},
+		teens:    [10]string{"десяти", "одиннадцати", "двенадцати", "тринадцати", "четырнадцати", "пятнадцати", "шестнадцати", "семнадцати", "восемнадцати", "девятнадцати"},
+		tens:     [10]string{"", "", "двадцати", "тридцати", "сорока", "пятидесяти", "шестидесяти", "семидесяти", "восьмидесяти", "девяноста"},
+		hundreds: [10]string{"", "ста", "двухстах", "трехстах", "четырехстах", "пятистах", "шестистах", "семистах", "восьмистах", "девятистах"},
+	},
+}
+
+// russianDeclensions - declensions of the scale words and the built-in currencies
+var russianDeclensions = map[string]Declension{
+	"тысяча":   {[4]string{"тысячи", "тысяче", "тысячей", "тысяче"}, [4]string{"тысяч", "тысячам", "тысячами", "тысячах"}},
+	"миллион":  {[4]string{"миллиона", "миллиону", "миллионом", "миллионе"}, [4]string{"миллионов", "миллионам", "миллионами", "миллионах"}},
+	"миллиард": {[4]string{"миллиарда", "миллиарду", "миллиардом", "миллиарде"}, [4]string{"миллиардов", "миллиардам", "миллиардами", "миллиардах"}},
+	"триллион": {[4]string{"триллиона", "триллиону", "триллионом", "триллионе"}, [4]string{"триллионов", "триллионам", "триллионами", "триллионах"}},
+	"рубль":    {[4]string{"рубля", "рублю", "рублем", "рубле"}, [4]string{"рублей", "рублям", "рублями", "рублях"}},
+	"копейка":  {[4]string{"копейки", "копейке", "копейкой", "копейке"}, [4]string{"копеек", "копейкам", "копейками", "копейках"}},
+	"доллар":   {[4]string{"доллара", "доллару", "долларом", "долларе"}, [4]string{"долларов", "долларам", "долларами", "долларах"}},
+	"цент":     {[4]string{"цента", "центу", "центом", "центе"}, [4]string{"центов", "центам", "центами", "центах"}},
+	"евро":     {[4]string{"евро", "евро", "евро", "евро"}, [4]string{"евро", "евро", "евро", "евро"}},
+	"тенге":    {[4]string{"тенге", "тенге", "тенге", "тенге"}, [4]string{"тенге", "тенге", "тенге", "тенге"}},
+	"тиын":     {[4]string{"тиына", "тиыну", "тиыном", "тиыне"}, [4]string{"тиынов", "тиынам", "тиынами", "тиынах"}},
+	"белорусский рубль": {
+		[4]string{"белорусского рубля", "белорусскому рублю", "белорусским рублем", "белорусском рубле"},
+		[4]string{"белорусских рублей", "белорусским рублям", "белорусскими рублями", "белорусских рублях"},
+	},
+	"гривна": {[4]string{"гривны", "гривне", "гривной", "гривне"}, [4]string{"гривен", "гривнам", "гривнами", "гривнах"}},
+	"юань":   {[4]string{"юаня", "юаню", "юанем", "юане"}, [4]string{"юаней", "юаням", "юанями", "юанях"}},
+	"фэнь":   {[4]string{"фэня", "фэню", "фэнем", "фэне"}, [4]string{"фэней", "фэням", "фэнями", "фэнях"}},
+}
+
+// nameSuffix - suffix of the defined names of the words in case c
+func (c GrammaticalCase) nameSuffix() string {
+	switch c {
+	case Genitive:
+		return "_GEN"
+	case Dative:
+		return "_DAT"
+	case Instrumental:
+		return "_INS"
+	case Prepositional:
+		return "_PRE"
+	}
+	return ""
+}
+
+// words - words of numbers in case c, false if the language doesn't have them
+func (l *Language) words(c GrammaticalCase) (caseWords, bool) {
+	if c == Nominative {
+		return caseWords{l.zero, l.ones, l.onesFem, l.teens, l.tens, l.hundreds}, true
+	}
+	w, ok := l.cases[c]
+	return w, ok
+}
+
+// caseForm - name of unit u in case c after number n, false if the declension of u is
+// unknown
+func (l *Language) caseForm(u Unit, n uint64, c GrammaticalCase) (string, bool) {
+	if c == Nominative {
+		return u.Forms[l.pluralForm(n)], true
+	}
+	d, ok := l.Declensions[u.Forms[0]]
+	switch {
+	case !ok:
+		return "", false
+	case n%1000 == 0:
+		// zero, thousand, million are nouns, the unit after them is genitive plural in
+		// any case: "тысячей рублей"
+		return d.Plural[0], true
+	case n%10 == 1 && n%100 != 11:
+		return d.Singular[c-1], true
+	}
+	return d.Plural[c-1], true
+}
+
+// unitName - name of unit u in case c after number n, or its abbreviation if abbr is set
+func (l *Language) unitName(u Unit, n uint64, c GrammaticalCase, abbr bool) string {
+	if abbr {
+		return u.Abbr
+	}
+	name, _ := l.caseForm(u, n, c)
+	return name
+}
+
+// scaleForms - forms of scale word u in case c by the last digit of its group, 0 is also
+// for teens. The group is never a multiple of 1000, so digit d stands for 20+d
+func (l *Language) scaleForms(u Unit, c GrammaticalCase) []string {
+	forms := make([]string, 10)
+	for d := range forms {
+		forms[d], _ = l.caseForm(u, uint64(20+d), c)
+	}
+	return forms
+}
+
+// checkCase - check that amounts in currency cur can be spelled in case c
+func (l *Language) checkCase(c GrammaticalCase, cur Currency) error {
+	if _, ok := l.words(c); !ok {
+		return fmt.Errorf("grammatical case %d isn't supported by language %s", c, l.Code)
+	}
+	if c == Nominative {
+		return nil
+	}
+	for _, u := range []Unit{l.thousand, l.million, l.billion, l.trillion, cur.Major, cur.Minor} {
+		if _, ok := l.Declensions[u.Forms[0]]; !ok {
+			return fmt.Errorf("no declension of %q in language %s", u.Forms[0], l.Code)
+		}
+	}
+	return nil
+}
+
+// caseNames - vocabulary of the language in case c as defined names used by the spell
+// formulas in this case
+func (l *Language) caseNames(prefix string, c GrammaticalCase) map[string]string {
+	p, s := namePrefix(prefix, l), c.nameSuffix()
+	w, _ := l.words(c)
+	return map[string]string{
+		p + "N_4" + s:  wordsArray(w.hundreds[:], ",", "z"),
+		p + "N_0X" + s: l.tensMatrix(false, c),
+		p + "N_1X" + s: l.tensMatrix(true, c),
+		p + "THS" + s:  scaleLookup(l.scaleForms(l.thousand, c)),
+		p + "MLN" + s:  scaleLookup(l.scaleForms(l.million, c)),
+		p + "BLN" + s:  scaleLookup(l.scaleForms(l.billion, c)),
+		p + "TRL" + s:  scaleLookup(l.scaleForms(l.trillion, c)),
+	}
+}
+
+// SetDefinedNamesCase - set defined names for the spell formulas of lang in grammatical
+// case c, the names of SetDefinedNamesPrefix are set as well, the names of the older
+// versions are replaced the same way. It's safe to call it again
+func SetDefinedNamesCase(wb *spreadsheet.Workbook, lang *Language, prefix string, c GrammaticalCase) error {
+	if !validNamePrefix(prefix) {
+		return fmt.Errorf("invalid defined name prefix %q", prefix)
+	}
+	if _, ok := lang.words(c); !ok {
+		return fmt.Errorf("grammatical case %d isn't supported by language %s", c, lang.Code)
+	}
+	names := lang.definedNames(prefix)
+	for k, v := range lang.caseNames(prefix, c) {
+		names[k] = v
+	}
+	if err := addDefinedNames(wb, names); err != nil {
+		return err
+	}
+	replaceLegacyNames(wb, namePrefix(prefix, lang))
+	return nil
+}
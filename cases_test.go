@@ -0,0 +1,97 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+	"testing"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestSpellAmountCases(t *testing.T) {
+	tests := []struct {
+		c      GrammaticalCase
+		amount float64
+		minor  MinorStyle
+		want   string
+	}{
+		{Genitive, 0, MinorDigits, "Нуля рублей 00 копеек"},
+		{Genitive, 1, MinorDigits, "Одного рубля 00 копеек"},
+		{Genitive, 2, MinorDigits, "Двух рублей 00 копеек"},
+		{Genitive, 5, MinorDigits, "Пяти рублей 00 копеек"},
+		{Genitive, 21, MinorDigits, "Двадцати одного рубля 00 копеек"},
+		{Genitive, 1000, MinorDigits, "Одной тысячи рублей 00 копеек"},
+		{Genitive, 2000, MinorDigits, "Двух тысяч рублей 00 копеек"},
+		{Genitive, 21000, MinorDigits, "Двадцати одной тысячи рублей 00 копеек"},
+		{Genitive, 1e6, MinorDigits, "Одного миллиона рублей 00 копеек"},
+		{Genitive, 1.01, MinorDigits, "Одного рубля 01 копейки"},
+		{Genitive, 2.5, MinorWords, "Двух рублей пятидесяти копеек"},
+
+		{Dative, 0, MinorDigits, "Нулю рублей 00 копеек"},
+		{Dative, 1, MinorDigits, "Одному рублю 00 копеек"},
+		{Dative, 2, MinorDigits, "Двум рублям 00 копеек"},
+		{Dative, 5, MinorDigits, "Пяти рублям 00 копеек"},
+		{Dative, 21, MinorDigits, "Двадцати одному рублю 00 копеек"},
+		{Dative, 1000, MinorDigits, "Одной тысяче рублей 00 копеек"},
+		{Dative, 2000, MinorDigits, "Двум тысячам рублей 00 копеек"},
+		{Dative, 1e6, MinorDigits, "Одному миллиону рублей 00 копеек"},
+		{Dative, 0.02, MinorDigits, "Нулю рублей 02 копейкам"},
+
+		{Instrumental, 0, MinorDigits, "Нулем рублей 00 копеек"},
+		{Instrumental, 1, MinorDigits, "Одним рублем 00 копеек"},
+		{Instrumental, 2, MinorDigits, "Двумя рублями 00 копеек"},
+		{Instrumental, 5, MinorDigits, "Пятью рублями 00 копеек"},
+		{Instrumental, 21, MinorDigits, "Двадцатью одним рублем 00 копеек"},
+		{Instrumental, 1000, MinorDigits, "Одной тысячей рублей 00 копеек"},
+		{Instrumental, 2000, MinorDigits, "Двумя тысячами рублей 00 копеек"},
+		{Instrumental, 1e6, MinorDigits, "Одним миллионом рублей 00 копеек"},
+		{Instrumental, 1.01, MinorDigits, "Одним рублем 01 копейкой"},
+
+		{Prepositional, 0, MinorDigits, "Нуле рублей 00 копеек"},
+		{Prepositional, 1, MinorDigits, "Одном рубле 00 копеек"},
+		{Prepositional, 2, MinorDigits, "Двух рублях 00 копеек"},
+		{Prepositional, 5, MinorDigits, "Пяти рублях 00 копеек"},
+		{Prepositional, 21, MinorDigits, "Двадцати одном рубле 00 копеек"},
+		{Prepositional, 1000, MinorDigits, "Одной тысяче рублей 00 копеек"},
+		{Prepositional, 2000, MinorDigits, "Двух тысячах рублей 00 копеек"},
+		{Prepositional, 1e6, MinorDigits, "Одном миллионе рублей 00 копеек"},
+		{Prepositional, 2e6, MinorDigits, "Двух миллионах рублей 00 копеек"},
+		{Prepositional, 0.02, MinorDigits, "Нуле рублей 02 копейках"},
+	}
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	for _, c := range []GrammaticalCase{Genitive, Dative, Instrumental, Prepositional} {
+		if err := SetDefinedNamesCase(wb, Russian, "", c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, tt := range tests {
+		opts := SpellOptions{GramCase: tt.c, Minor: tt.minor}
+		if got := SpellAmountOptions(tt.amount, opts); got != tt.want {
+			t.Errorf("SpellAmountOptions(%v, case %d) = %q, want %q", tt.amount, tt.c, got, tt.want)
+		}
+		ref := fmt.Sprintf("A%d", i+1)
+		sheet.Cell(ref).SetNumber(tt.amount)
+		if err := SetSpellFormulaOptions(sheet.Cell(fmt.Sprintf("B%d", i+1)), ref, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	RecalculateFormulas(wb)
+	for i, tt := range tests {
+		if got := sheet.Cell(fmt.Sprintf("B%d", i+1)).GetString(); got != tt.want {
+			t.Errorf("formula of %v in case %d = %q, want %q", tt.amount, tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestSpellAmountCasesUnsupported(t *testing.T) {
+	opts := SpellOptions{Language: English, Currency: USD, GramCase: Genitive}
+	if got := SpellAmountOptions(1, opts); got != "" {
+		t.Errorf("English genitive = %q, want empty", got)
+	}
+	if _, err := GetSpellFormulaOptions("A1", opts); err == nil {
+		t.Error("English genitive formula is built")
+	}
+	if err := SetDefinedNamesCase(spreadsheet.New(), English, "", Genitive); err == nil {
+		t.Error("English genitive names are set")
+	}
+}
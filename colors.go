@@ -0,0 +1,378 @@
+package gooxmlhelpers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"baliance.com/gooxml"
+	"baliance.com/gooxml/color"
+	"baliance.com/gooxml/schema/soo/dml"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// kinds of Color
+const (
+	colorNone = iota
+	colorRGB
+	colorIndexed
+	colorTheme
+)
+
+// Theme color indices of ThemeColor, the light and dark colors go in this order in
+// the styles unlike the theme itself
+const (
+	ThemeLight1 uint32 = iota
+	ThemeDark1
+	ThemeLight2
+	ThemeDark2
+	ThemeAccent1
+	ThemeAccent2
+	ThemeAccent3
+	ThemeAccent4
+	ThemeAccent5
+	ThemeAccent6
+	ThemeHyperlink
+	ThemeFollowedHyperlink
+)
+
+// Color - color of a fill, font or border: RGB, a color of the indexed palette or a theme
+// color with tint. Theme colors change with the theme of the workbook. The zero Color
+// isn't set
+type Color struct {
+	kind  int
+	rgb   color.Color
+	index uint32
+	tint  float64
+}
+
+// RGBColor - color clr
+func RGBColor(clr color.Color) Color {
+	return Color{kind: colorRGB, rgb: clr}
+}
+
+// IndexedColor - color i of the indexed palette, 64 is the system foreground and 65 the
+// system background
+func IndexedColor(i uint32) Color {
+	return Color{kind: colorIndexed, index: i}
+}
+
+// ThemeColor - theme color (ThemeAccent1, ...) lightened by positive tint or darkened by
+// negative one, -1 to 1: "Accent 1, lighter 40%" is ThemeColor(ThemeAccent1, 0.4)
+func ThemeColor(theme uint32, tint float64) Color {
+	return Color{kind: colorTheme, index: theme, tint: tint}
+}
+
+// IsZero - check if the color isn't set
+func (c Color) IsZero() bool {
+	return c.kind == colorNone
+}
+
+// String - description of the color: "FF0000", "indexed 10", "theme 4 tint 0.4"
+func (c Color) String() string {
+	switch c.kind {
+	case colorRGB:
+		return strings.ToUpper(*c.rgb.AsRGBString())
+	case colorIndexed:
+		return fmt.Sprintf("indexed %d", c.index)
+	case colorTheme:
+		if c.tint != 0 {
+			return fmt.Sprintf("theme %d tint %g", c.index, c.tint)
+		}
+		return fmt.Sprintf("theme %d", c.index)
+	}
+	return "none"
+}
+
+// x - color of the stylesheet, nil for the zero color
+func (c Color) x() *sml.CT_Color {
+	x := sml.NewCT_Color()
+	switch c.kind {
+	case colorRGB:
+		x.RgbAttr = c.rgb.AsRGBAString()
+	case colorIndexed:
+		x.IndexedAttr = gooxml.Uint32(c.index)
+	case colorTheme:
+		x.ThemeAttr = gooxml.Uint32(c.index)
+		if c.tint != 0 {
+			x.TintAttr = gooxml.Float64(c.tint)
+		}
+	default:
+		return nil
+	}
+	return x
+}
+
+// colorOf - Color of stylesheet color x, automatic color is the system foreground
+func colorOf(x *sml.CT_Color) Color {
+	switch {
+	case x == nil:
+		return Color{}
+	case x.ThemeAttr != nil:
+		tint := 0.0
+		if x.TintAttr != nil {
+			tint = *x.TintAttr
+		}
+		return ThemeColor(*x.ThemeAttr, tint)
+	case x.RgbAttr != nil:
+		if clr, ok := parseRGB(*x.RgbAttr); ok {
+			return RGBColor(clr)
+		}
+	case x.IndexedAttr != nil:
+		return IndexedColor(*x.IndexedAttr)
+	case x.AutoAttr != nil && *x.AutoAttr:
+		return IndexedColor(64)
+	}
+	return Color{}
+}
+
+// parseRGB - color of hex "RRGGBB" or "AARRGGBB", the alpha is ignored as Excel does
+func parseRGB(s string) (color.Color, bool) {
+	if len(s) == 8 {
+		s = s[2:]
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if len(s) != 6 || err != nil {
+		return color.Color{}, false
+	}
+	return color.RGB(uint8(v>>16), uint8(v>>8), uint8(v)), true
+}
+
+// indexedPalette - default colors of the indexed palette, then the system foreground and
+// background
+var indexedPalette = []string{
+	"000000", "FFFFFF", "FF0000", "00FF00", "0000FF", "FFFF00", "FF00FF", "00FFFF",
+	"000000", "FFFFFF", "FF0000", "00FF00", "0000FF", "FFFF00", "FF00FF", "00FFFF",
+	"800000", "008000", "000080", "808000", "800080", "008080", "C0C0C0", "808080",
+	"9999FF", "993366", "FFFFCC", "CCFFFF", "660066", "FF8080", "0066CC", "CCCCFF",
+	"000080", "FF00FF", "FFFF00", "00FFFF", "800080", "800000", "008080", "0000FF",
+	"00CCFF", "CCFFFF", "CCFFCC", "FFFF99", "99CCFF", "FF99CC", "CC99FF", "FFCC99",
+	"3366FF", "33CCCC", "99CC00", "FFCC00", "FF9900", "FF6600", "666699", "969696",
+	"003366", "339966", "003300", "333300", "993300", "993366", "333399", "333333",
+	"000000", "FFFFFF",
+}
+
+// officeTheme - colors of the default Office theme by theme color index, they are used
+// when the workbook has no theme or it can't be read
+var officeTheme = []string{
+	"FFFFFF", "000000", "EEECE1", "1F497D", "4F81BD", "C0504D",
+	"9BBB59", "8064A2", "4BACC6", "F79646", "0000FF", "800080",
+}
+
+// ColorResolver - effective RGB of colors in a workbook: theme colors are taken from
+// the theme of the workbook and indexed ones from its palette
+type ColorResolver struct {
+	theme   []string
+	indexed []string
+}
+
+// NewColorResolver - resolver of the colors of wb, the theme is read once, create the
+// resolver once for many colors. When the theme can't be read the error is logged by
+// gooxml.Log and the colors of the default Office theme are used
+func NewColorResolver(wb *spreadsheet.Workbook) *ColorResolver {
+	r := &ColorResolver{
+		theme:   append([]string{}, officeTheme...),
+		indexed: append([]string{}, indexedPalette...),
+	}
+	if x := wb.StyleSheet.X(); x != nil && x.Colors != nil && x.Colors.IndexedColors != nil {
+		for i, c := range x.Colors.IndexedColors.RgbColor {
+			if i < len(r.indexed) && c.RgbAttr != nil {
+				r.indexed[i] = *c.RgbAttr
+			}
+		}
+	}
+	thm, err := workbookTheme(wb)
+	if err != nil {
+		gooxml.Log("can't read workbook theme: %s", err)
+	}
+	if thm != nil && thm.ThemeElements != nil && thm.ThemeElements.ClrScheme != nil {
+		s := thm.ThemeElements.ClrScheme
+		for i, c := range []*dml.CT_Color{s.Lt1, s.Dk1, s.Lt2, s.Dk2, s.Accent1, s.Accent2, s.Accent3,
+			s.Accent4, s.Accent5, s.Accent6, s.Hlink, s.FolHlink} {
+			switch {
+			case c == nil:
+			case c.SrgbClr != nil:
+				r.theme[i] = c.SrgbClr.ValAttr
+			case c.SysClr != nil && c.SysClr.LastClrAttr != nil:
+				r.theme[i] = *c.SysClr.LastClrAttr
+			}
+		}
+	}
+	return r
+}
+
+// workbookTheme - the first theme of wb, nil if it has none. gooxml doesn't export the
+// themes, they are taken from the unexported field, a workbook of gooxml without it is
+// saved to memory to read the theme part
+func workbookTheme(wb *spreadsheet.Workbook) (*dml.Theme, error) {
+	if v := reflect.ValueOf(wb).Elem().FieldByName("themes"); v.IsValid() &&
+		v.Type() == reflect.TypeOf([]*dml.Theme(nil)) {
+		if v.Len() == 0 {
+			return nil, nil
+		}
+		return (*dml.Theme)(unsafe.Pointer(v.Index(0).Pointer())), nil
+	}
+	return savedTheme(wb)
+}
+
+// savedTheme - the first theme of wb read from the saved workbook, nil if it has none
+func savedTheme(wb *spreadsheet.Workbook) (*dml.Theme, error) {
+	var buf bytes.Buffer
+	if err := wb.Save(&buf); err != nil {
+		return nil, err
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		return nil, err
+	}
+	name := gooxml.AbsoluteFilename(gooxml.DocTypeSpreadsheet, gooxml.ThemeType, 1)
+	for _, f := range z.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		b, err := ioutil.ReadAll(rc)
+		if err != nil {
+			return nil, err
+		}
+		thm := dml.NewTheme()
+		if err := xml.Unmarshal(b, thm); err != nil {
+			return nil, fmt.Errorf("can't read theme: %s", err)
+		}
+		return thm, nil
+	}
+	return nil, nil
+}
+
+// RGB - effective RGB of color c, the zero color is black
+func (r *ColorResolver) RGB(c Color) (color.Color, error) {
+	switch c.kind {
+	case colorNone:
+		return color.RGB(0, 0, 0), nil
+	case colorRGB:
+		return c.rgb, nil
+	case colorIndexed:
+		if int(c.index) >= len(r.indexed) {
+			return color.Color{}, fmt.Errorf("no indexed color %d", c.index)
+		}
+		clr, ok := parseRGB(r.indexed[c.index])
+		if !ok {
+			return color.Color{}, fmt.Errorf("invalid indexed color %d %q", c.index, r.indexed[c.index])
+		}
+		return clr, nil
+	}
+	if int(c.index) >= len(r.theme) {
+		return color.Color{}, fmt.Errorf("no theme color %d", c.index)
+	}
+	clr, ok := parseRGB(r.theme[c.index])
+	if !ok {
+		return color.Color{}, fmt.Errorf("invalid theme color %d %q", c.index, r.theme[c.index])
+	}
+	return applyTint(clr, c.tint), nil
+}
+
+// hlsMax - range of the HLS components in Excel, they are integers like in ColorRGBToHLS
+// of Windows and rgbMax is the range of RGB ones
+const (
+	hlsMax = 240
+	rgbMax = 255
+)
+
+// applyTint - clr lightened or darkened by tint as Excel does: the lightness of HLS is
+// moved towards white or black in the integer arithmetic of Windows
+func applyTint(clr color.Color, tint float64) color.Color {
+	if tint == 0 {
+		return clr
+	}
+	h, l, s := rgbToHLS(clr)
+	if tint < 0 {
+		l = int(float64(l) * (1 + tint))
+	} else {
+		l = int(float64(l)*(1-tint) + hlsMax*tint)
+	}
+	return hlsToRGB(h, l, s)
+}
+
+// rgbToHLS - hue, lightness and saturation of clr from 0 to hlsMax
+func rgbToHLS(clr color.Color) (h, l, s int) {
+	rgb, _ := strconv.ParseUint(*clr.AsRGBString(), 16, 32)
+	r, g, b := int(rgb>>16), int(rgb>>8&0xff), int(rgb&0xff)
+	max, min := r, r
+	for _, v := range []int{g, b} {
+		if v > max {
+			max = v
+		}
+		if v < min {
+			min = v
+		}
+	}
+	l = ((max+min)*hlsMax + rgbMax) / (2 * rgbMax)
+	if max == min {
+		// the hue is undefined
+		return hlsMax * 2 / 3, l, 0
+	}
+	if l <= hlsMax/2 {
+		s = ((max-min)*hlsMax + (max+min)/2) / (max + min)
+	} else {
+		s = ((max-min)*hlsMax + (2*rgbMax-max-min)/2) / (2*rgbMax - max - min)
+	}
+	delta := func(v int) int { return ((max-v)*(hlsMax/6) + (max-min)/2) / (max - min) }
+	switch max {
+	case r:
+		h = delta(b) - delta(g)
+	case g:
+		h = hlsMax/3 + delta(r) - delta(b)
+	default:
+		h = 2*hlsMax/3 + delta(g) - delta(r)
+	}
+	if h < 0 {
+		h += hlsMax
+	}
+	if h > hlsMax {
+		h -= hlsMax
+	}
+	return h, l, s
+}
+
+// hlsToRGB - color of hue h, lightness l and saturation s from 0 to hlsMax
+func hlsToRGB(h, l, s int) color.Color {
+	if s == 0 {
+		v := uint8(l * rgbMax / hlsMax)
+		return color.RGB(v, v, v)
+	}
+	m2 := (l*(hlsMax+s) + hlsMax/2) / hlsMax
+	if l > hlsMax/2 {
+		m2 = l + s - (l*s+hlsMax/2)/hlsMax
+	}
+	m1 := 2*l - m2
+	hue := func(h int) uint8 {
+		if h < 0 {
+			h += hlsMax
+		}
+		if h > hlsMax {
+			h -= hlsMax
+		}
+		v := m1
+		switch {
+		case h < hlsMax/6:
+			v = m1 + ((m2-m1)*h+hlsMax/12)/(hlsMax/6)
+		case h < hlsMax/2:
+			v = m2
+		case h < hlsMax*2/3:
+			v = m1 + ((m2-m1)*(hlsMax*2/3-h)+hlsMax/12)/(hlsMax/6)
+		}
+		return uint8((v*rgbMax + hlsMax/2) / hlsMax)
+	}
+	return color.RGB(hue(h+hlsMax/3), hue(h), hue(h-hlsMax/3))
+}
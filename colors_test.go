@@ -0,0 +1,200 @@
+package gooxmlhelpers
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"baliance.com/gooxml"
+	"baliance.com/gooxml/color"
+	"baliance.com/gooxml/common"
+	"baliance.com/gooxml/schema/soo/dml"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestFillColorTheme(t *testing.T) {
+	wb := spreadsheet.New()
+	ss, sheet := wb.StyleSheet, wb.AddSheet()
+	idx, err := FillColorChecked(ss, sheet.Cell("A1"), ThemeColor(4, 0.4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FillColor(ss, sheet.Cell("A2"), ThemeColor(4, 0.4)); got != idx {
+		t.Errorf("same theme fill got style %d, want %d", got, idx)
+	}
+	if err := FillColorRange(ss, sheet, "B1:B2", IndexedColor(10)); err != nil {
+		t.Fatal(err)
+	}
+	for ref, want := range map[string]Color{"A1": ThemeColor(4, 0.4), "B2": IndexedColor(10)} {
+		xf := ss.X().CellXfs.Xf[*sheet.Cell(ref).X().SAttr]
+		fill := ss.X().Fills.Fill[*xf.FillIdAttr]
+		if got := colorOf(fill.PatternFill.FgColor); got != want {
+			t.Errorf("%s fill color %s, want %s", ref, got, want)
+		}
+	}
+}
+
+func TestNewColorResolverBrokenWorkbook(t *testing.T) {
+	wb := spreadsheet.New()
+	wb.AddSheet()
+	x := wb.StyleSheet.X()
+	x.Colors = sml.NewCT_Colors()
+	x.Colors.IndexedColors = sml.NewCT_IndexedColors()
+	for i := 0; i < 10; i++ {
+		x.Colors.IndexedColors.RgbColor = append(x.Colors.IndexedColors.RgbColor, &sml.CT_RgbColor{RgbAttr: gooxml.String("FF123456")})
+	}
+	// the workbook can't be saved, the theme is read without saving it
+	wb.ExtraFiles = append(wb.ExtraFiles, common.ExtraFile{ZipPath: "xl/missing.bin", DiskPath: filepath.Join(t.TempDir(), "missing.bin")})
+
+	r := NewColorResolver(wb)
+	for _, tt := range []struct {
+		c    Color
+		want color.Color
+	}{
+		{ThemeColor(4, 0), color.RGB(0x4F, 0x81, 0xBD)},
+		{ThemeColor(1, 0), color.RGB(0, 0, 0)},
+		{IndexedColor(3), color.RGB(0x12, 0x34, 0x56)},
+		{IndexedColor(10), color.RGB(0xFF, 0, 0)},
+	} {
+		got, err := r.RGB(tt.c)
+		if err != nil {
+			t.Errorf("RGB(%s): %s", tt.c, err)
+		} else if got != tt.want {
+			t.Errorf("RGB(%s) = %s, want %s", tt.c, *got.AsRGBString(), *tt.want.AsRGBString())
+		}
+	}
+}
+
+func TestApplyTint(t *testing.T) {
+	// the colors of the Office 2007 theme with the tints of the color picker of Excel
+	tests := []struct {
+		rgb  string
+		tint float64
+		want string
+	}{
+		{"4F81BD", 0.7999, "DCE6F1"},
+		{"4F81BD", 0.5999, "B8CCE4"},
+		{"4F81BD", 0.3999, "95B3D7"},
+		{"4F81BD", -0.2499, "366092"},
+		{"4F81BD", -0.4999, "244062"},
+		{"C0504D", 0.7999, "F2DCDB"},
+		{"C0504D", 0.5999, "E6B8B7"},
+		{"C0504D", 0.3999, "DA9694"},
+		{"C0504D", -0.2499, "963634"},
+		{"C0504D", -0.4999, "632523"},
+		{"FFFFFF", -0.0499, "F2F2F2"},
+		{"FFFFFF", -0.2499, "BFBFBF"},
+		{"FFFFFF", -0.4999, "7F7F7F"},
+		{"4F81BD", 0, "4F81BD"},
+	}
+	for _, tt := range tests {
+		clr, _ := parseRGB(tt.rgb)
+		if got := strings.ToUpper(*applyTint(clr, tt.tint).AsRGBString()); got != tt.want {
+			t.Errorf("applyTint(%s, %g) = %s, want %s", tt.rgb, tt.tint, got, tt.want)
+		}
+	}
+}
+
+// customThemeWorkbook - workbook with a theme of accent1 112233 and dark 1 of the system
+// color 010203 read from a file
+func customThemeWorkbook(t *testing.T) *spreadsheet.Workbook {
+	t.Helper()
+	wb := spreadsheet.New()
+	wb.AddSheet()
+	var buf bytes.Buffer
+	if err := wb.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const theme = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<a:theme xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" name="Custom"><a:themeElements>
+<a:clrScheme name="Custom"><a:dk1><a:sysClr val="windowText" lastClr="010203"/></a:dk1><a:lt1><a:srgbClr val="FEFEFE"/></a:lt1>
+<a:dk2><a:srgbClr val="202020"/></a:dk2><a:lt2><a:srgbClr val="E0E0E0"/></a:lt2><a:accent1><a:srgbClr val="112233"/></a:accent1>
+<a:accent2><a:srgbClr val="C0504D"/></a:accent2><a:accent3><a:srgbClr val="9BBB59"/></a:accent3><a:accent4><a:srgbClr val="8064A2"/></a:accent4>
+<a:accent5><a:srgbClr val="4BACC6"/></a:accent5><a:accent6><a:srgbClr val="F79646"/></a:accent6><a:hlink><a:srgbClr val="0000FF"/></a:hlink>
+<a:folHlink><a:srgbClr val="800080"/></a:folHlink></a:clrScheme>
+<a:fontScheme name="Custom"><a:majorFont><a:latin typeface="Cambria"/><a:ea typeface=""/><a:cs typeface=""/></a:majorFont>
+<a:minorFont><a:latin typeface="Calibri"/><a:ea typeface=""/><a:cs typeface=""/></a:minorFont></a:fontScheme>
+<a:fmtScheme name="Custom"><a:fillStyleLst/><a:lnStyleLst/><a:effectStyleLst/><a:bgFillStyleLst/></a:fmtScheme>
+</a:themeElements></a:theme>`
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch f.Name {
+		case "[Content_Types].xml":
+			b = bytes.Replace(b, []byte("</Types>"), []byte(`<Override ContentType="application/vnd.openxmlformats-officedocument.theme+xml" PartName="/xl/theme/theme1.xml"/></Types>`), 1)
+		case "xl/_rels/workbook.xml.rels":
+			b = bytes.Replace(b, []byte("</Relationships>"), []byte(`<Relationship Target="theme/theme1.xml" Type="`+gooxml.ThemeType+`" Id="rId99"/></Relationships>`), 1)
+		}
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(b)
+	}
+	w, err := zw.Create("xl/theme/theme1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(theme))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	wb, err = spreadsheet.Read(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wb
+}
+
+func TestNewColorResolverCustomTheme(t *testing.T) {
+	wb := customThemeWorkbook(t)
+	for name, read := range map[string]func(*spreadsheet.Workbook) (*dml.Theme, error){
+		"workbookTheme": workbookTheme, "savedTheme": savedTheme,
+	} {
+		thm, err := read(wb)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if thm == nil || thm.ThemeElements.ClrScheme.Accent1.SrgbClr.ValAttr != "112233" {
+			t.Errorf("%s didn't read the custom theme", name)
+		}
+	}
+	if thm, err := workbookTheme(spreadsheet.New()); thm != nil || err != nil {
+		t.Errorf("workbookTheme of a new workbook = %v, %v, want no theme", thm, err)
+	}
+
+	r := NewColorResolver(wb)
+	for _, tt := range []struct {
+		c    Color
+		want color.Color
+	}{
+		{ThemeColor(4, 0), color.RGB(0x11, 0x22, 0x33)},
+		{ThemeColor(1, 0), color.RGB(0x01, 0x02, 0x03)},
+		{ThemeColor(0, 0), color.RGB(0xFE, 0xFE, 0xFE)},
+		{ThemeColor(5, -0.2499), color.RGB(0x96, 0x36, 0x34)},
+	} {
+		got, err := r.RGB(tt.c)
+		if err != nil {
+			t.Errorf("RGB(%s): %s", tt.c, err)
+		} else if got != tt.want {
+			t.Errorf("RGB(%s) = %s, want %s", tt.c, *got.AsRGBString(), *tt.want.AsRGBString())
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package gooxmlhelpers
+
+// Gender - grammatical gender of a unit name, it selects "один"/"одна", "два"/"две"
+type Gender int
+
+// Gender constants
+const (
+	Masculine Gender = iota
+	Feminine
+)
+
+// Unit - unit name with its gender, three plural forms: for 1, 2 and 5
+// ("рубль", "рубля", "рублей") and abbreviation ("руб.")
+type Unit struct {
+	Gender Gender
+	Forms  [3]string
+	Abbr   string
+}
+
+// Currency - names of major and minor (1/100 of major) currency units used to spell amounts
+type Currency struct {
+	Code  string
+	Major Unit
+	Minor Unit
+}
+
+// Built-in currencies with Russian unit names
+var (
+	RUB = Currency{
+		Code:  "RUB",
+		Major: Unit{Masculine, [3]string{"рубль", "рубля", "рублей"}, "руб."},
+		Minor: Unit{Feminine, [3]string{"копейка", "копейки", "копеек"}, "коп."},
+	}
+	USD = Currency{
+		Code:  "USD",
+		Major: Unit{Masculine, [3]string{"доллар", "доллара", "долларов"}, "долл."},
+		Minor: Unit{Masculine, [3]string{"цент", "цента", "центов"}, "цент."},
+	}
+	EUR = Currency{
+		Code:  "EUR",
+		Major: Unit{Masculine, [3]string{"евро", "евро", "евро"}, "евро"},
+		Minor: Unit{Masculine, [3]string{"цент", "цента", "центов"}, "цент."},
+	}
+	KZT = Currency{
+		Code:  "KZT",
+		Major: Unit{Masculine, [3]string{"тенге", "тенге", "тенге"}, "тнг."},
+		Minor: Unit{Masculine, [3]string{"тиын", "тиына", "тиынов"}, "тиын."},
+	}
+	BYN = Currency{
+		Code:  "BYN",
+		Major: Unit{Masculine, [3]string{"белорусский рубль", "белорусских рубля", "белорусских рублей"}, "бел. руб."},
+		Minor: Unit{Feminine, [3]string{"копейка", "копейки", "копеек"}, "коп."},
+	}
+	UAH = Currency{
+		Code:  "UAH",
+		Major: Unit{Feminine, [3]string{"гривна", "гривны", "гривен"}, "грн."},
+		Minor: Unit{Feminine, [3]string{"копейка", "копейки", "копеек"}, "коп."},
+	}
+	CNY = Currency{
+		Code:  "CNY",
+		Major: Unit{Masculine, [3]string{"юань", "юаня", "юаней"}, "юан."},
+		Minor: Unit{Masculine, [3]string{"фэнь", "фэня", "фэней"}, "фэн."},
+	}
+)
+
+// Currencies - built-in currencies by ISO 4217 code
+var Currencies = map[string]Currency{
+	RUB.Code: RUB,
+	USD.Code: USD,
+	EUR.Code: EUR,
+	KZT.Code: KZT,
+	BYN.Code: BYN,
+	UAH.Code: UAH,
+	CNY.Code: CNY,
+}
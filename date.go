@@ -0,0 +1,230 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// maxSerialDate is the serial number of 1 Jan 10000 in the 1900 date system, the first
+// date Excel doesn't support, and date1904Offset is the number of days between the epochs
+const (
+	maxSerialDate  = 2958466
+	date1904Offset = 1462
+)
+
+// Russian words for dates: ordinals of days in nominative neuter ("первое") and of years in
+// genitive masculine ("первого"), months in genitive
+var (
+	dayOrdinals = [20]string{"", "первое", "второе", "третье", "четвертое", "пятое", "шестое", "седьмое", "восьмое", "девятое",
+		"десятое", "одиннадцатое", "двенадцатое", "тринадцатое", "четырнадцатое", "пятнадцатое", "шестнадцатое", "семнадцатое", "восемнадцатое", "девятнадцатое"}
+	dayTensOrdinals = [4]string{"", "", "двадцатое", "тридцатое"}
+	yearOrdinals    = [20]string{"", "первого", "второго", "третьего", "четвертого", "пятого", "шестого", "седьмого", "восьмого", "девятого",
+		"десятого", "одиннадцатого", "двенадцатого", "тринадцатого", "четырнадцатого", "пятнадцатого", "шестнадцатого", "семнадцатого", "восемнадцатого", "девятнадцатого"}
+	yearTensOrdinals      = [10]string{"", "", "двадцатого", "тридцатого", "сорокового", "пятидесятого", "шестидесятого", "семидесятого", "восьмидесятого", "девяностого"}
+	yearHundredsOrdinals  = [10]string{"", "сотого", "двухсотого", "трехсотого", "четырехсотого", "пятисотого", "шестисотого", "семисотого", "восьмисотого", "девятисотого"}
+	yearThousandsOrdinals = [10]string{"", "тысячного", "двухтысячного", "трехтысячного", "четырехтысячного", "пятитысячного", "шеститысячного", "семитысячного", "восьмитысячного", "девятитысячного"}
+	monthsGenitive        = [12]string{"января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+)
+
+// SpellDate - return date in words (Russian), the same text as the GetSpellDateFormula
+// formula gives: "восемнадцатое октября две тысячи двадцать шестого года"
+func SpellDate(d time.Time) string {
+	return spellDate(d.Date())
+}
+
+// SpellDateSerial - same as SpellDate for Excel serial date, it's converted in the date
+// system of wb (1900 or 1904). Empty string for serials out of the Excel range, in the 1900
+// system serial 0 is day 0 of January 1900 and it's out of the range as well
+func SpellDateSerial(wb *spreadsheet.Workbook, serial float64) string {
+	if !serialInRange(serial, wb.Uses1904Dates()) {
+		return ""
+	}
+	return spellDate(serialDate(serial, wb.Uses1904Dates()))
+}
+
+// SerialToTime - convert Excel serial date to time in UTC, uses1904 selects the 1904 date
+// system. In the 1900 system serial 60 is 29 Feb 1900 that never existed, 1 Mar 1900 is
+// returned for it
+func SerialToTime(serial float64, uses1904 bool) time.Time {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	switch {
+	case uses1904:
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	case serial < 61:
+		// Excel counts 29 Feb 1900, so earlier dates are one day closer to the epoch
+		epoch = epoch.AddDate(0, 0, 1)
+	}
+	days := int(serial)
+	ns := time.Duration((serial - float64(days)) * float64(24*time.Hour))
+	return epoch.AddDate(0, 0, days).Add(ns.Round(time.Millisecond))
+}
+
+// serialInRange - check if serial is a date Excel supports, the 1900 system starts with
+// serial 1 and the 1904 one with serial 0
+func serialInRange(serial float64, uses1904 bool) bool {
+	min, max := 1.0, float64(maxSerialDate)
+	if uses1904 {
+		min, max = 0, max-date1904Offset
+	}
+	return serial >= min && serial < max
+}
+
+// serialDate - year, month and day of Excel serial date as Excel sees them, including
+// 29 Feb 1900 and 0 Jan 1900
+func serialDate(serial float64, uses1904 bool) (int, time.Month, int) {
+	switch {
+	case uses1904:
+	case int(serial) == 0:
+		return 1900, time.January, 0
+	case int(serial) == 60:
+		return 1900, time.February, 29
+	}
+	return SerialToTime(serial, uses1904).Date()
+}
+
+// spellDate - date in words, empty for years out of 1..9999
+func spellDate(y int, m time.Month, d int) string {
+	if y < 1 || y > 9999 {
+		return ""
+	}
+	words := []string{ordinal(d, dayOrdinals[:], dayTensOrdinals[:]), monthsGenitive[m-1]}
+	words = append(words, yearWords(y)...)
+	return strings.Join(append(words, "года"), " ")
+}
+
+// ordinal - spell n from 0 (empty) to 99 as ordinal, only the last word is ordinal: "двадцать первое"
+func ordinal(n int, ones, tens []string) string {
+	switch {
+	case n < 20:
+		return ones[n]
+	case n%10 == 0:
+		return tens[n/10]
+	}
+	return Russian.tens[n/10] + " " + ones[n%10]
+}
+
+// yearWords - words of year as genitive ordinal: "две тысячи двадцать шестого"
+func yearWords(y int) []string {
+	var words []string
+	th, h, r := y/1000, y/100%10, y%100
+	switch {
+	case th == 0:
+	case y%1000 == 0:
+		return []string{yearThousandsOrdinals[th]}
+	default:
+		words = append(words, yearThousands(th))
+	}
+	switch {
+	case h == 0:
+	case r == 0:
+		return append(words, yearHundredsOrdinals[h])
+	default:
+		words = append(words, Russian.hundreds[h])
+	}
+	return append(words, ordinal(r, yearOrdinals[:], yearTensOrdinals[:]))
+}
+
+// yearThousands - thousands of year: "тысяча", "две тысячи"
+func yearThousands(th int) string {
+	switch th {
+	case 0:
+		return ""
+	case 1:
+		return Russian.thousand.Forms[0]
+	}
+	return Russian.onesFem[th] + " " + Russian.thousand.Forms[pluralForm(uint64(th))]
+}
+
+// dateNames - defined names used by the date formula with prefix p. Parts of year are
+// indexed from zero (nothing) and followed by space, so the formula needs no checks for
+// missing parts: the engines evaluate all IF branches and INDEX fails on column 0
+func dateNames(p string) map[string]string {
+	var days, years, thousands []string
+	for d := 1; d <= 31; d++ {
+		days = append(days, ordinal(d, dayOrdinals[:], dayTensOrdinals[:]))
+	}
+	for y := 0; y <= 99; y++ {
+		years = append(years, ordinal(y, yearOrdinals[:], yearTensOrdinals[:]))
+	}
+	for th := 0; th <= 9; th++ {
+		thousands = append(thousands, yearThousands(th))
+	}
+	return map[string]string{
+		p + "D_DAY": plainArray(days, ""),
+		p + "D_MON": plainArray(monthsGenitive[:], ""),
+		p + "D_YRO": plainArray(years, " "),
+		p + "D_HUN": plainArray(Russian.hundreds[:], " "),
+		p + "D_HUO": plainArray(yearHundredsOrdinals[:], " "),
+		p + "D_THS": plainArray(thousands, " "),
+		p + "D_THO": plainArray(yearThousandsOrdinals[:], " "),
+	}
+}
+
+// SetDefinedNamesDate - set defined names for GetSpellDateFormula, it's safe to call it again
+func SetDefinedNamesDate(wb *spreadsheet.Workbook) error {
+	return SetDefinedNamesDatePrefix(wb, "")
+}
+
+// SetDefinedNamesDatePrefix - set defined names for GetSpellDateFormulaPrefix with the same
+// prefix ("GH_D_DAY", prefix is made upper case), it's safe to call it again
+func SetDefinedNamesDatePrefix(wb *spreadsheet.Workbook, prefix string) error {
+	if !validNamePrefix(prefix) {
+		return fmt.Errorf("invalid defined name prefix %q", prefix)
+	}
+	return addDefinedNames(wb, dateNames(strings.ToUpper(prefix)))
+}
+
+// GetSpellDateFormula - return formula spelling ref date in words (Russian), you need to run
+// SetDefinedNamesDate(). Excel converts the serial date in the date system of the workbook
+func GetSpellDateFormula(ref string) (string, error) {
+	return GetSpellDateFormulaPrefix(ref, "")
+}
+
+// GetSpellDateFormulaPrefix - same as GetSpellDateFormula, but uses defined names set by
+// SetDefinedNamesDatePrefix with the same prefix
+func GetSpellDateFormulaPrefix(ref, prefix string) (string, error) {
+	ref, err := checkSpellRef(ref, prefix)
+	if err != nil {
+		return "", err
+	}
+	p := strings.ToUpper(prefix)
+	y := "YEAR(" + ref + ")"
+	th, h := "INT("+y+"/1000)+1", "MOD(INT("+y+"/100),10)+1"
+	f := fmt.Sprintf(`=INDEX(%[5]sD_DAY,1,DAY(%[1]s))&" "&INDEX(%[5]sD_MON,1,MONTH(%[1]s))&" "&`+
+		`IF(MOD(%[2]s,1000),INDEX(%[5]sD_THS,1,%[3]s),INDEX(%[5]sD_THO,1,%[3]s))&`+
+		`IF(MOD(%[2]s,100),INDEX(%[5]sD_HUN,1,%[4]s),INDEX(%[5]sD_HUO,1,%[4]s))&`+
+		`INDEX(%[5]sD_YRO,1,MOD(%[2]s,100)+1)&"года"`, ref, y, th, h, p)
+	if err := checkFormula(f); err != nil {
+		return "", fmt.Errorf("can't build date formula for %q: %s", ref, err)
+	}
+	if err := checkFormulaLen(f); err != nil {
+		return "", fmt.Errorf("can't build date formula for %q: %w", ref, err)
+	}
+	return f, nil
+}
+
+// SetSpellDateFormula - convert ref cell date to words (Russian), you need to run
+// SetDefinedNamesDate()
+func SetSpellDateFormula(cell spreadsheet.Cell, ref string) error {
+	f, err := GetSpellDateFormula(ref)
+	if err != nil {
+		return err
+	}
+	cell.SetFormulaRaw(f)
+	return nil
+}
+
+// plainArray - array constant of words as they are, non-empty ones followed by suffix
+func plainArray(words []string, suffix string) string {
+	items := make([]string, len(words))
+	for i, w := range words {
+		if w != "" {
+			w += suffix
+		}
+		items[i] = formulaString(w)
+	}
+	return "{" + strings.Join(items, ",") + "}"
+}
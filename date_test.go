@@ -0,0 +1,165 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestSpellDate(t *testing.T) {
+	tests := []struct {
+		y    int
+		m    time.Month
+		d    int
+		want string
+	}{
+		{1900, time.January, 1, "первое января тысяча девятисотого года"},
+		{1901, time.March, 2, "второе марта тысяча девятьсот первого года"},
+		{2000, time.December, 31, "тридцать первое декабря двухтысячного года"},
+		{2011, time.May, 21, "двадцать первое мая две тысячи одиннадцатого года"},
+		{2100, time.February, 28, "двадцать восьмое февраля две тысячи сотого года"},
+		{9999, time.December, 31, "тридцать первое декабря девять тысяч девятьсот девяносто девятого года"},
+		{2026, time.October, 18, "восемнадцатое октября две тысячи двадцать шестого года"},
+		{10000, time.January, 1, ""},
+	}
+	for _, tt := range tests {
+		d := time.Date(tt.y, tt.m, tt.d, 15, 30, 0, 0, time.UTC)
+		if got := SpellDate(d); got != tt.want {
+			t.Errorf("SpellDate(%s) = %q, want %q", d.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestSerialToTime(t *testing.T) {
+	tests := []struct {
+		serial   float64
+		uses1904 bool
+		want     time.Time
+	}{
+		{1, false, time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{59, false, time.Date(1900, 2, 28, 0, 0, 0, 0, time.UTC)},
+		{60, false, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{61, false, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{367, false, time.Date(1901, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{36526, false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{40725.5, false, time.Date(2011, 7, 1, 12, 0, 0, 0, time.UTC)},
+		{73051, false, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{2958465, false, time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{0, true, time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{35064.25, true, time.Date(2000, 1, 1, 6, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := SerialToTime(tt.serial, tt.uses1904); !got.Equal(tt.want) {
+			t.Errorf("SerialToTime(%v, %t) = %s, want %s", tt.serial, tt.uses1904, got, tt.want)
+		}
+	}
+}
+
+// newDateWorkbook - workbook in the 1900 or 1904 date system
+func newDateWorkbook(uses1904 bool) *spreadsheet.Workbook {
+	wb := spreadsheet.New()
+	if uses1904 {
+		if wb.X().WorkbookPr == nil {
+			wb.X().WorkbookPr = sml.NewCT_WorkbookPr()
+		}
+		wb.X().WorkbookPr.Date1904Attr = &uses1904
+	}
+	return wb
+}
+
+func TestSpellDateSerial(t *testing.T) {
+	tests := []struct {
+		serial   float64
+		uses1904 bool
+		want     string
+	}{
+		{0, false, ""},
+		{0.5, false, ""},
+		{-1, false, ""},
+		{1, false, "первое января тысяча девятисотого года"},
+		{60, false, "двадцать девятое февраля тысяча девятисотого года"},
+		{61, false, "первое марта тысяча девятисотого года"},
+		{367, false, "первое января тысяча девятьсот первого года"},
+		{36891.75, false, "тридцать первое декабря двухтысячного года"},
+		{40684, false, "двадцать первое мая две тысячи одиннадцатого года"},
+		{73109, false, "двадцать восьмое февраля две тысячи сотого года"},
+		{2958465, false, "тридцать первое декабря девять тысяч девятьсот девяносто девятого года"},
+		{2958466, false, ""},
+		{0, true, "первое января тысяча девятьсот четвертого года"},
+		{60, true, "первое марта тысяча девятьсот четвертого года"},
+		{39222, true, "двадцать первое мая две тысячи одиннадцатого года"},
+		{2957003, true, "тридцать первое декабря девять тысяч девятьсот девяносто девятого года"},
+		{2957004, true, ""},
+		{-1, true, ""},
+	}
+	for _, tt := range tests {
+		if got := SpellDateSerial(newDateWorkbook(tt.uses1904), tt.serial); got != tt.want {
+			t.Errorf("SpellDateSerial(%v) in 1904 %t = %q, want %q", tt.serial, tt.uses1904, got, tt.want)
+		}
+	}
+}
+
+func TestRecalculateDateFormula(t *testing.T) {
+	for _, uses1904 := range []bool{false, true} {
+		serials := []float64{1, 59, 60, 61, 367, 1000, 36526, 36891.75, 40684, 46313, 73109, 2957003, 2958465}
+		if uses1904 {
+			serials = append(serials, 0)
+		}
+		for _, prefix := range []string{"", "gh_"} {
+			wb := newDateWorkbook(uses1904)
+			sheet := wb.AddSheet()
+			if err := SetDefinedNamesDatePrefix(wb, prefix); err != nil {
+				t.Fatal(err)
+			}
+			for i, serial := range serials {
+				sheet.Cell(fmt.Sprintf("A%d", i+1)).SetNumber(serial)
+				f, err := GetSpellDateFormulaPrefix(fmt.Sprintf("A%d", i+1), prefix)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sheet.Cell(fmt.Sprintf("B%d", i+1)).SetFormulaRaw(f)
+			}
+			RecalculateFormulas(wb)
+			for i, serial := range serials {
+				want := SpellDateSerial(wb, serial)
+				got := sheet.Cell(fmt.Sprintf("B%d", i+1)).GetString()
+				if want == "" && strings.HasPrefix(got, "#") {
+					// out of the range the formula gives an error
+					continue
+				}
+				if got != want {
+					t.Errorf("1904 %t, prefix %q: date formula of %v = %q, want %q", uses1904, prefix, serial, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSetDefinedNamesDatePrefix(t *testing.T) {
+	wb := spreadsheet.New()
+	// a name of the user clashing with the unprefixed date names
+	wb.AddDefinedName("D_DAY", "Sheet1!$A$1")
+	if err := SetDefinedNamesDate(wb); err == nil {
+		t.Error("SetDefinedNamesDate replaced the name of the user")
+	}
+	if err := SetDefinedNamesDatePrefix(wb, "GH_"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetDefinedNamesDatePrefix(wb, "gh_"); err != nil {
+		t.Errorf("second call: %s", err)
+	}
+	if err := SetDefinedNamesDatePrefix(wb, "1X"); err == nil {
+		t.Error("invalid prefix accepted")
+	}
+	names := workbookNames(wb)
+	if len(names) != 8 || names["GH_D_DAY"] == "" || names["D_DAY"] != "Sheet1!$A$1" {
+		t.Errorf("names %v, want 7 prefixed names and the name of the user", names)
+	}
+	if _, err := GetSpellDateFormulaPrefix("A1", "1X"); err == nil {
+		t.Error("invalid prefix accepted by GetSpellDateFormulaPrefix")
+	}
+}
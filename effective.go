@@ -0,0 +1,271 @@
+package gooxmlhelpers
+
+import (
+	"errors"
+	"fmt"
+
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+	"baliance.com/gooxml/spreadsheet/reference"
+)
+
+// Sources of EffectiveStyle
+const (
+	StyleOfCell    = "cell"
+	StyleOfRow     = "row"
+	StyleOfColumn  = "column"
+	StyleOfDefault = "default"
+)
+
+// EffectiveStyle - resolved style of a cell: where it comes from and the attributes of
+// its font, fill, borders, alignment, number format and protection
+type EffectiveStyle struct {
+	// Source - StyleOfCell, StyleOfRow, StyleOfColumn or StyleOfDefault
+	Source string
+	// Index - index of the cell style in the stylesheet
+	Index     uint32
+	Font      FontStyle
+	Fill      FillSpec
+	Border    BorderStyle
+	Alignment AlignmentStyle
+	// NumberFormatID, NumberFormat - id and code of the number format, built-in ones
+	// get the code of gooxml
+	NumberFormatID uint32
+	NumberFormat   string
+	// Locked, Hidden - protection of the cell, it works when the sheet is protected
+	Locked bool
+	Hidden bool
+}
+
+// FontStyle - font of EffectiveStyle, Color is zero when it's automatic
+type FontStyle struct {
+	Name      string
+	Size      float64
+	Bold      bool
+	Italic    bool
+	Underline bool
+	Strike    bool
+	Color     Color
+}
+
+// BorderLine - side of BorderStyle, the style is sml.ST_BorderStyleUnset when there's no line
+type BorderLine struct {
+	Style sml.ST_BorderStyle
+	Color Color
+}
+
+// BorderStyle - borders of EffectiveStyle
+type BorderStyle struct {
+	Left, Right, Top, Bottom, Diagonal BorderLine
+}
+
+// AlignmentStyle - alignment of EffectiveStyle
+type AlignmentStyle struct {
+	Horizontal  sml.ST_HorizontalAlignment
+	Vertical    sml.ST_VerticalAlignment
+	Wrap        bool
+	ShrinkToFit bool
+	Indent      uint32
+	Rotation    uint8
+}
+
+// GetEffectiveStyle - resolved style of cell ref ("B10") of sheet. A cell without style of
+// its own takes the style of its row (when the row has a custom format), then the style
+// of its column, then the default style. The cell isn't created when it's missing
+func GetEffectiveStyle(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet, ref string) (EffectiveStyle, error) {
+	cr, err := reference.ParseCellReference(ref)
+	if err != nil {
+		return EffectiveStyle{}, fmt.Errorf("invalid cell reference %q: %s", ref, err)
+	}
+	x := ss.X()
+	if x == nil || x.CellXfs == nil || len(x.CellXfs.Xf) == 0 {
+		return EffectiveStyle{}, errors.New("stylesheet has no cell styles")
+	}
+	es := EffectiveStyle{Source: StyleOfDefault}
+	source, idx := styleSource(sheet, cr)
+	if source != "" {
+		es.Source, es.Index = source, idx
+	}
+	if int(es.Index) >= len(x.CellXfs.Xf) {
+		return EffectiveStyle{}, fmt.Errorf("%s of cell %s has style %d, but there are %d styles", es.Source, ref, es.Index, len(x.CellXfs.Xf))
+	}
+	xf := x.CellXfs.Xf[es.Index]
+	if xf == nil {
+		return EffectiveStyle{}, fmt.Errorf("%s of cell %s has style %d, but it's empty", es.Source, ref, es.Index)
+	}
+	if err := es.setFont(x, xf); err != nil {
+		return EffectiveStyle{}, err
+	}
+	if err := es.setFill(x, xf); err != nil {
+		return EffectiveStyle{}, err
+	}
+	if err := es.setBorder(x, xf); err != nil {
+		return EffectiveStyle{}, err
+	}
+	if a := xf.Alignment; a != nil {
+		es.Alignment = AlignmentStyle{
+			Horizontal:  a.HorizontalAttr,
+			Vertical:    a.VerticalAttr,
+			Wrap:        a.WrapTextAttr != nil && *a.WrapTextAttr,
+			ShrinkToFit: a.ShrinkToFitAttr != nil && *a.ShrinkToFitAttr,
+		}
+		if a.IndentAttr != nil {
+			es.Alignment.Indent = *a.IndentAttr
+		}
+		if a.TextRotationAttr != nil {
+			es.Alignment.Rotation = *a.TextRotationAttr
+		}
+	}
+	if xf.NumFmtIdAttr != nil {
+		es.NumberFormatID = *xf.NumFmtIdAttr
+	}
+	es.NumberFormat = numberFormatCode(x, es.NumberFormatID)
+	// cells are locked by default
+	es.Locked = true
+	if p := xf.Protection; p != nil {
+		es.Locked = p.LockedAttr == nil || *p.LockedAttr
+		es.Hidden = p.HiddenAttr != nil && *p.HiddenAttr
+	}
+	return es, nil
+}
+
+// styleSource - where the style of cell cr comes from and its index, "" for the default
+func styleSource(sheet spreadsheet.Sheet, cr reference.CellReference) (string, uint32) {
+	name := cr.Column + fmt.Sprint(cr.RowIdx)
+	if data := sheet.X().SheetData; data != nil {
+		for _, row := range data.Row {
+			if row.RAttr == nil || *row.RAttr != cr.RowIdx {
+				continue
+			}
+			for _, c := range row.C {
+				if c.RAttr != nil && *c.RAttr == name && c.SAttr != nil {
+					return StyleOfCell, *c.SAttr
+				}
+			}
+			if row.SAttr != nil && row.CustomFormatAttr != nil && *row.CustomFormatAttr {
+				return StyleOfRow, *row.SAttr
+			}
+			break
+		}
+	}
+	for _, cols := range sheet.X().Cols {
+		for _, col := range cols.Col {
+			if col.MinAttr <= cr.ColumnIdx+1 && cr.ColumnIdx+1 <= col.MaxAttr && col.StyleAttr != nil {
+				return StyleOfColumn, *col.StyleAttr
+			}
+		}
+	}
+	return "", 0
+}
+
+// setFont - set the font of es from style xf of stylesheet x
+func (es *EffectiveStyle) setFont(x *sml.StyleSheet, xf *sml.CT_Xf) error {
+	id := uint32(0)
+	if xf.FontIdAttr != nil {
+		id = *xf.FontIdAttr
+	}
+	if x.Fonts == nil || int(id) >= len(x.Fonts.Font) {
+		if xf.FontIdAttr == nil {
+			return nil
+		}
+		return fmt.Errorf("style %d has font %d, but it doesn't exist", es.Index, id)
+	}
+	f := x.Fonts.Font[id]
+	if f == nil {
+		return fmt.Errorf("style %d has font %d, but it's empty", es.Index, id)
+	}
+	on := func(p []*sml.CT_BooleanProperty) bool {
+		return len(p) > 0 && (p[0].ValAttr == nil || *p[0].ValAttr)
+	}
+	es.Font = FontStyle{
+		Bold:      on(f.B),
+		Italic:    on(f.I),
+		Strike:    on(f.Strike),
+		Underline: len(f.U) > 0 && f.U[0].ValAttr != sml.ST_UnderlineValuesNone,
+	}
+	if len(f.Name) > 0 {
+		es.Font.Name = f.Name[0].ValAttr
+	}
+	if len(f.Sz) > 0 {
+		es.Font.Size = f.Sz[0].ValAttr
+	}
+	if len(f.Color) > 0 && (f.Color[0].AutoAttr == nil || !*f.Color[0].AutoAttr) {
+		es.Font.Color = colorOf(f.Color[0])
+	}
+	return nil
+}
+
+// setFill - set the fill of es from style xf of stylesheet x
+func (es *EffectiveStyle) setFill(x *sml.StyleSheet, xf *sml.CT_Xf) error {
+	if xf.FillIdAttr == nil {
+		es.Fill = FillSpec{Pattern: sml.ST_PatternTypeNone}
+		return nil
+	}
+	if x.Fills == nil || int(*xf.FillIdAttr) >= len(x.Fills.Fill) {
+		return fmt.Errorf("style %d has fill %d, but it doesn't exist", es.Index, *xf.FillIdAttr)
+	}
+	f := x.Fills.Fill[*xf.FillIdAttr]
+	if f == nil {
+		return fmt.Errorf("style %d has fill %d, but it's empty", es.Index, *xf.FillIdAttr)
+	}
+	switch {
+	case f.GradientFill != nil:
+		g := f.GradientFill
+		gs := &GradientSpec{Path: g.TypeAttr == sml.ST_GradientTypePath}
+		for _, a := range []struct {
+			v    *float64
+			attr *float64
+		}{{&gs.Degree, g.DegreeAttr}, {&gs.Left, g.LeftAttr}, {&gs.Right, g.RightAttr}, {&gs.Top, g.TopAttr}, {&gs.Bottom, g.BottomAttr}} {
+			if a.attr != nil {
+				*a.v = *a.attr
+			}
+		}
+		for _, st := range g.Stop {
+			gs.Stops = append(gs.Stops, GradientStop{st.PositionAttr, colorOf(st.Color)})
+		}
+		es.Fill = FillSpec{Gradient: gs}
+	case f.PatternFill != nil:
+		p := f.PatternFill
+		es.Fill = FillSpec{Pattern: p.PatternTypeAttr, FgColor: colorOf(p.FgColor), BgColor: colorOf(p.BgColor)}
+		if p.PatternTypeAttr == sml.ST_PatternTypeUnset {
+			es.Fill.Pattern = sml.ST_PatternTypeNone
+		}
+	default:
+		es.Fill = FillSpec{Pattern: sml.ST_PatternTypeNone}
+	}
+	return nil
+}
+
+// setBorder - set the borders of es from style xf of stylesheet x
+func (es *EffectiveStyle) setBorder(x *sml.StyleSheet, xf *sml.CT_Xf) error {
+	if xf.BorderIdAttr == nil {
+		return nil
+	}
+	if x.Borders == nil || int(*xf.BorderIdAttr) >= len(x.Borders.Border) {
+		return fmt.Errorf("style %d has border %d, but it doesn't exist", es.Index, *xf.BorderIdAttr)
+	}
+	b := x.Borders.Border[*xf.BorderIdAttr]
+	if b == nil {
+		return fmt.Errorf("style %d has border %d, but it's empty", es.Index, *xf.BorderIdAttr)
+	}
+	line := func(pr *sml.CT_BorderPr) BorderLine {
+		if pr == nil {
+			return BorderLine{}
+		}
+		return BorderLine{pr.StyleAttr, colorOf(pr.Color)}
+	}
+	es.Border = BorderStyle{line(b.Left), line(b.Right), line(b.Top), line(b.Bottom), line(b.Diagonal)}
+	return nil
+}
+
+// numberFormatCode - code of number format id in stylesheet x
+func numberFormatCode(x *sml.StyleSheet, id uint32) string {
+	if x.NumFmts != nil {
+		for _, nf := range x.NumFmts.NumFmt {
+			if nf != nil && nf.NumFmtIdAttr == id {
+				return nf.FormatCodeAttr
+			}
+		}
+	}
+	return spreadsheet.CreateDefaultNumberFormat(spreadsheet.StandardFormat(id)).X().FormatCodeAttr
+}
@@ -0,0 +1,136 @@
+package gooxmlhelpers
+
+import (
+	"strings"
+	"testing"
+
+	"baliance.com/gooxml"
+	"baliance.com/gooxml/color"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestGetEffectiveStyle(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	ss := wb.StyleSheet
+	yellow := RGBColor(color.Yellow)
+	sheet.Cell("A10").SetNumber(1234.5)
+	// row 10 of a report: bold on yellow with the format of the Russian locale
+	err := ModifyRangeStyle(ss, sheet, "10:10", func(d *StyleDelta) {
+		d.SetBold(true)
+		d.SetFillColor(yellow)
+		d.SetNumberFormat("# ##0,00")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ModifyRangeStyle(ss, sheet, "C:C", func(d *StyleDelta) {
+		d.SetItalic(true)
+		d.SetLocked(false)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	SetNumberFormat(ss, sheet.Cell("E1"), "0.00")
+
+	tests := []struct {
+		ref    string
+		source string
+		bold   bool
+		italic bool
+		fill   bool
+		locked bool
+		id     uint32
+		format string
+	}{
+		{"A10", StyleOfCell, true, false, true, true, 200, "# ##0,00"},
+		// no cell, the style of the row
+		{"B10", StyleOfRow, true, false, true, true, 200, "# ##0,00"},
+		// the cell of the styled column in the row gets both
+		{"C10", StyleOfCell, true, true, true, false, 200, "# ##0,00"},
+		{"C5", StyleOfColumn, false, true, false, false, 0, "General"},
+		{"D5", StyleOfDefault, false, false, false, true, 0, "General"},
+		{"E1", StyleOfCell, false, false, false, true, 2, "0.00"},
+	}
+	for _, tt := range tests {
+		es, err := GetEffectiveStyle(ss, sheet, tt.ref)
+		if err != nil {
+			t.Fatalf("%s: %s", tt.ref, err)
+		}
+		if es.Source != tt.source {
+			t.Errorf("%s: style of %s, want %s", tt.ref, es.Source, tt.source)
+		}
+		if es.Font.Bold != tt.bold || es.Font.Italic != tt.italic {
+			t.Errorf("%s: bold %v, italic %v, want %v, %v", tt.ref, es.Font.Bold, es.Font.Italic, tt.bold, tt.italic)
+		}
+		if filled := es.Fill.Pattern == sml.ST_PatternTypeSolid && es.Fill.FgColor == yellow; filled != tt.fill {
+			t.Errorf("%s: fill %v of %s, want yellow %v", tt.ref, es.Fill.Pattern, es.Fill.FgColor, tt.fill)
+		}
+		if es.Locked != tt.locked {
+			t.Errorf("%s: locked %v, want %v", tt.ref, es.Locked, tt.locked)
+		}
+		if es.NumberFormatID != tt.id || es.NumberFormat != tt.format {
+			t.Errorf("%s: number format %d %q, want %d %q", tt.ref, es.NumberFormatID, es.NumberFormat, tt.id, tt.format)
+		}
+	}
+
+	// a built-in number format without an entry in the stylesheet
+	ss.X().CellXfs.Xf = append(ss.X().CellXfs.Xf, &sml.CT_Xf{NumFmtIdAttr: gooxml.Uint32(9)})
+	sheet.Cell("F1").SetStyleIndex(uint32(len(ss.X().CellXfs.Xf) - 1))
+	es, err := GetEffectiveStyle(ss, sheet, "F1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if es.NumberFormatID != 9 || es.NumberFormat != "0%" {
+		t.Errorf("built-in number format %d %q, want 9 \"0%%\"", es.NumberFormatID, es.NumberFormat)
+	}
+}
+
+func TestGetEffectiveStyleBroken(t *testing.T) {
+	tests := []struct {
+		name   string
+		break_ func(x *sml.StyleSheet, xf *sml.CT_Xf)
+		err    string
+	}{
+		{"nil cell style", func(x *sml.StyleSheet, xf *sml.CT_Xf) { x.CellXfs.Xf[len(x.CellXfs.Xf)-1] = nil }, "empty"},
+		{"missing font", func(x *sml.StyleSheet, xf *sml.CT_Xf) { xf.FontIdAttr = gooxml.Uint32(99) }, "font 99"},
+		{"nil font", func(x *sml.StyleSheet, xf *sml.CT_Xf) { x.Fonts.Font[*xf.FontIdAttr] = nil }, "font"},
+		{"missing fill", func(x *sml.StyleSheet, xf *sml.CT_Xf) { xf.FillIdAttr = gooxml.Uint32(99) }, "fill 99"},
+		{"nil fill", func(x *sml.StyleSheet, xf *sml.CT_Xf) { x.Fills.Fill[*xf.FillIdAttr] = nil }, "fill"},
+		{"missing border", func(x *sml.StyleSheet, xf *sml.CT_Xf) { xf.BorderIdAttr = gooxml.Uint32(99) }, "border 99"},
+		{"nil border", func(x *sml.StyleSheet, xf *sml.CT_Xf) { x.Borders.Border[*xf.BorderIdAttr] = nil }, "border"},
+		{"nil number format", func(x *sml.StyleSheet, xf *sml.CT_Xf) {
+			x.NumFmts.NumFmt = append([]*sml.CT_NumFmt{nil}, x.NumFmts.NumFmt...)
+		}, ""},
+	}
+	for _, tt := range tests {
+		wb := spreadsheet.New()
+		sheet := wb.AddSheet()
+		ss := wb.StyleSheet
+		cell := sheet.Cell("A1")
+		ModifyCellStyle(ss, cell, func(d *StyleDelta) {
+			d.SetBold(true)
+			d.SetFillColor(RGBColor(color.Red))
+			d.SetBorder(BorderAll, sml.ST_BorderStyleThin, Color{})
+			d.SetNumberFormat("0.000")
+		})
+		x := ss.X()
+		tt.break_(x, x.CellXfs.Xf[*cell.X().SAttr])
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: panic: %v", tt.name, r)
+				}
+			}()
+			es, err := GetEffectiveStyle(ss, sheet, "A1")
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("%s: unexpected error: %s", tt.name, err)
+			case tt.err == "" && es.NumberFormat != "0.000":
+				t.Errorf("%s: number format %q, want \"0.000\"", tt.name, es.NumberFormat)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+		}()
+	}
+}
@@ -0,0 +1,474 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"baliance.com/gooxml"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+	"baliance.com/gooxml/spreadsheet/format"
+	"baliance.com/gooxml/spreadsheet/formula"
+	"baliance.com/gooxml/spreadsheet/reference"
+)
+
+// functions - functions used by the spell formulas, but missing in the gooxml formula engine
+var functions = map[string]formula.Function{
+	"CHOOSE":     excelChoose,
+	"MID":        excelMid,
+	"SUBSTITUTE": excelSubstitute,
+	"TEXT":       excelText,
+	"VALUE":      excelValue,
+}
+
+// complexFunctions - same as functions for the functions which need the formula context
+var complexFunctions = map[string]formula.FunctionComplex{
+	"DAY":   datePart("DAY", func(y int, m time.Month, d int) int { return d }),
+	"MONTH": datePart("MONTH", func(y int, m time.Month, d int) int { return int(m) }),
+	"YEAR":  datePart("YEAR", func(y int, m time.Month, d int) int { return y }),
+}
+
+var registerOnce sync.Once
+
+// RegisterFunctions - register the functions used by the spell formulas, but missing in the
+// gooxml formula engine: CHOOSE, MID, SUBSTITUTE, TEXT, VALUE, DAY, MONTH and YEAR. The
+// functions already registered are kept. It's called by FormulaContext and SetSpellValue,
+// call it to evaluate the spell formulas in other contexts
+func RegisterFunctions() {
+	registerOnce.Do(func() {
+		for name, fn := range functions {
+			if !isFunction(name) {
+				formula.RegisterFunction(name, fn)
+			}
+		}
+		for name, fn := range complexFunctions {
+			if !isFunction(name) {
+				formula.RegisterFunctionComplex(name, fn)
+			}
+		}
+	})
+}
+
+// isFunction - check if name is a function registered in the gooxml formula engine
+func isFunction(name string) bool {
+	return formula.LookupFunction(name) != nil || formula.LookupFunctionComplex(name) != nil
+}
+
+// excelChoose - implementation of Excel CHOOSE(index,value1,...) for the gooxml formula
+// engine, all the values are evaluated before the call
+func excelChoose(args []formula.Result) formula.Result {
+	if len(args) < 2 {
+		return formula.MakeErrorResult("CHOOSE requires index and values")
+	}
+	i := args[0].AsNumber()
+	switch {
+	case i.Type == formula.ResultTypeError:
+		return i
+	case i.Type != formula.ResultTypeNumber:
+		return formula.MakeErrorResult("CHOOSE requires numeric index")
+	case i.ValueNumber < 1 || int(i.ValueNumber) >= len(args):
+		return formula.MakeErrorResult("CHOOSE index out of range")
+	}
+	return args[int(i.ValueNumber)]
+}
+
+// excelMid - implementation of Excel MID(text,start,n) for the gooxml formula engine
+func excelMid(args []formula.Result) formula.Result {
+	if len(args) != 3 {
+		return formula.MakeErrorResult("MID requires three arguments")
+	}
+	s := args[0].AsString()
+	if s.Type != formula.ResultTypeString && s.Type != formula.ResultTypeEmpty {
+		return formula.MakeErrorResult("MID requires string argument")
+	}
+	start, n := args[1].AsNumber(), args[2].AsNumber()
+	if start.Type != formula.ResultTypeNumber || n.Type != formula.ResultTypeNumber {
+		return formula.MakeErrorResult("MID requires numeric start and length")
+	}
+	if start.ValueNumber < 1 || n.ValueNumber < 0 {
+		return formula.MakeErrorResult("MID start must be positive and length not negative")
+	}
+	runes := []rune(s.ValueString)
+	from := int(start.ValueNumber) - 1
+	if from >= len(runes) {
+		return formula.MakeStringResult("")
+	}
+	to := len(runes)
+	if float64(to-from) > n.ValueNumber {
+		to = from + int(n.ValueNumber)
+	}
+	return formula.MakeStringResult(string(runes[from:to]))
+}
+
+// excelSubstitute - implementation of Excel SUBSTITUTE(text,old,new,[n]) for the gooxml
+// formula engine
+func excelSubstitute(args []formula.Result) formula.Result {
+	if len(args) != 3 && len(args) != 4 {
+		return formula.MakeErrorResult("SUBSTITUTE requires three or four arguments")
+	}
+	var s [3]string
+	for i := range s {
+		r := args[i].AsString()
+		switch r.Type {
+		case formula.ResultTypeString:
+			s[i] = r.ValueString
+		case formula.ResultTypeEmpty:
+		default:
+			return formula.MakeErrorResult("SUBSTITUTE requires string arguments")
+		}
+	}
+	text, old, repl := s[0], s[1], s[2]
+	switch {
+	case old == "":
+		return formula.MakeStringResult(text)
+	case len(args) == 3:
+		return formula.MakeStringResult(strings.Replace(text, old, repl, -1))
+	}
+	n := args[3].AsNumber()
+	if n.Type != formula.ResultTypeNumber || n.ValueNumber < 1 {
+		return formula.MakeErrorResult("SUBSTITUTE requires positive instance number")
+	}
+	// replace only the n-th occurrence
+	idx := 0
+	for i := 1; ; i++ {
+		j := strings.Index(text[idx:], old)
+		if j < 0 {
+			return formula.MakeStringResult(text)
+		}
+		idx += j
+		if i == int(n.ValueNumber) {
+			return formula.MakeStringResult(text[:idx] + repl + text[idx+len(old):])
+		}
+		idx += len(old)
+	}
+}
+
+// excelText - implementation of Excel TEXT(value,format) for the gooxml formula engine.
+// The number is rounded the way Excel does before it's formatted, text is returned as is
+func excelText(args []formula.Result) formula.Result {
+	if len(args) != 2 {
+		return formula.MakeErrorResult("TEXT requires two arguments")
+	}
+	f := args[1].AsString()
+	if f.Type != formula.ResultTypeString {
+		return formula.MakeErrorResult("TEXT requires string format")
+	}
+	v := args[0].AsNumber()
+	switch {
+	case v.Type == formula.ResultTypeNumber && (math.IsNaN(v.ValueNumber) || math.IsInf(v.ValueNumber, 0)):
+		return formula.MakeErrorResultType(formula.ErrorTypeNum, "TEXT requires finite number")
+	case v.Type == formula.ResultTypeNumber:
+	case v.Type == formula.ResultTypeString:
+		return v
+	default:
+		return formula.MakeErrorResult("TEXT requires number argument")
+	}
+	decimals := formatDecimals(f.ValueString)
+	if v.ValueNumber >= 0 && zeroPadded(f.ValueString) {
+		// format.Number loses digits of big numbers, the spell formulas use such formats
+		text := excelFixed(v.ValueNumber, decimals)
+		if pad := strings.IndexByte(f.ValueString+".", '.') - strings.IndexByte(text+".", '.'); pad > 0 {
+			text = strings.Repeat("0", pad) + text
+		}
+		return formula.MakeStringResult(text)
+	}
+	n := excelRound(v.ValueNumber, decimals)
+	return formula.MakeStringResult(format.Number(n, f.ValueString))
+}
+
+// excelValue - implementation of Excel VALUE(text) for the gooxml formula engine
+func excelValue(args []formula.Result) formula.Result {
+	if len(args) != 1 {
+		return formula.MakeErrorResult("VALUE requires one argument")
+	}
+	switch args[0].Type {
+	case formula.ResultTypeNumber, formula.ResultTypeEmpty:
+		return args[0].AsNumber()
+	case formula.ResultTypeString:
+		v, err := strconv.ParseFloat(strings.TrimSpace(args[0].ValueString), 64)
+		if err != nil {
+			return formula.MakeErrorResult(fmt.Sprintf("VALUE can't convert %q to number", args[0].ValueString))
+		}
+		return formula.MakeNumberResult(v)
+	case formula.ResultTypeError:
+		return args[0]
+	}
+	return formula.MakeErrorResult("VALUE requires string argument")
+}
+
+// datePart - implementation of Excel DAY, MONTH and YEAR for the gooxml formula engine,
+// the serial date is converted in the date system of the workbook
+func datePart(name string, part func(int, time.Month, int) int) formula.FunctionComplex {
+	return func(ctx formula.Context, ev formula.Evaluator, args []formula.Result) formula.Result {
+		if len(args) != 1 {
+			return formula.MakeErrorResult(name + " requires one argument")
+		}
+		v := args[0].AsNumber()
+		if v.Type != formula.ResultTypeNumber {
+			return formula.MakeErrorResult(name + " requires serial date")
+		}
+		fc, _ := ctx.(formulaContext)
+		// Excel gives day 0 of January 1900 for serial 0
+		if !serialInRange(v.ValueNumber, fc.uses1904) && !(v.ValueNumber >= 0 && v.ValueNumber < 1) {
+			return formula.MakeErrorResultType(formula.ErrorTypeNum, name+" serial date out of range")
+		}
+		return formula.MakeNumberResult(float64(part(serialDate(v.ValueNumber, fc.uses1904))))
+	}
+}
+
+// formatDecimals - number of digits after the decimal point in the first section of
+// number format f
+func formatDecimals(f string) int {
+	n := 0
+	dot, quote := false, false
+	for _, r := range f {
+		switch {
+		case r == '"':
+			quote = !quote
+		case quote:
+		case r == ';':
+			return n
+		case r == '.':
+			dot = true
+		case dot && (r == '0' || r == '#' || r == '?'):
+			n++
+		}
+	}
+	return n
+}
+
+// excelRound - round v to decimals the way Excel does: keep 15 significant digits,
+// then round half away from zero
+func excelRound(v float64, decimals int) float64 {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return v
+	}
+	r, _ := strconv.ParseFloat(excelFixed(v, decimals), 64)
+	return math.Copysign(r, v)
+}
+
+// excelFixed - absolute value of finite v rounded like excelRound as decimal text with
+// given decimals. Unlike formatting of the rounded float it keeps all 15 digits exact
+func excelFixed(v float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'e', 14, 64)
+	e := strings.IndexByte(s, 'e')
+	exp, _ := strconv.Atoi(s[e+1:])
+	// |v| == 0.digits * 10^(exp+1)
+	digits := s[:1] + s[2:e]
+	keep := exp + 1 + decimals
+	var n string
+	switch {
+	case keep >= len(digits):
+		n = digits + strings.Repeat("0", keep-len(digits))
+	case keep < 0:
+		n = "0"
+	default:
+		r, _ := strconv.ParseUint("0"+digits[:keep], 10, 64)
+		if digits[keep] >= '5' {
+			r++
+		}
+		n = strconv.FormatUint(r, 10)
+	}
+	if decimals == 0 {
+		return n
+	}
+	if len(n) <= decimals {
+		n = strings.Repeat("0", decimals-len(n)+1) + n
+	}
+	return n[:len(n)-decimals] + "." + n[len(n)-decimals:]
+}
+
+// zeroPadded - check if number format f is zeros with optional decimal zeros: "000.00"
+func zeroPadded(f string) bool {
+	whole := strings.TrimRight(strings.TrimRight(f, "0"), ".")
+	return whole != "" && strings.Trim(whole, "0") == "" && strings.Count(f, ".") <= 1 &&
+		!strings.HasSuffix(f, ".")
+}
+
+// formulaContext - formula context of a sheet which also resolves defined names holding
+// array constants and formulas, the gooxml sheet context resolves only ranges. It also
+// keeps the workbook date system for DAY, MONTH and YEAR and the LAMBDA defined names
+// called by the evaluator of NewEvaluator
+type formulaContext struct {
+	formula.Context
+	uses1904 bool
+	lambdas  map[string]string
+	wb       *spreadsheet.Workbook
+	sheet    spreadsheet.Sheet
+	offset   *cellOffset
+}
+
+// cellOffset - offset of the relative references set by SetOffset
+type cellOffset struct {
+	col, row uint32
+}
+
+// FormulaContext - return formula context of sheet of wb for the evaluator of NewEvaluator,
+// unlike sheet.FormulaContext() it resolves the defined names of the spell formulas and
+// keeps the LAMBDA defined names of wb
+func FormulaContext(wb *spreadsheet.Workbook, sheet spreadsheet.Sheet) formula.Context {
+	RegisterFunctions()
+	return newFormulaContext(wb, sheet)
+}
+
+// newFormulaContext - formulaContext of sheet of wb, with nil wb it doesn't read numbers
+// of other sheets in exponent form and doesn't call lambdas
+func newFormulaContext(wb *spreadsheet.Workbook, sheet spreadsheet.Sheet) formulaContext {
+	c := formulaContext{Context: sheet.FormulaContext(), wb: wb, sheet: sheet, offset: &cellOffset{}}
+	if wb != nil {
+		c.uses1904, c.lambdas = wb.Uses1904Dates(), workbookLambdas(wb)
+	}
+	return c
+}
+
+// Sheet - context of other sheet resolving defined names the same way
+func (c formulaContext) Sheet(name string) formula.Context {
+	other := c
+	other.Context, other.sheet, other.offset = c.Context.Sheet(name), spreadsheet.Sheet{}, &cellOffset{}
+	if c.wb != nil {
+		for _, sheet := range c.wb.Sheets() {
+			if sheet.Name() == name {
+				other.sheet = sheet
+			}
+		}
+	}
+	return other
+}
+
+// SetOffset - set offset of the relative references for the cells of a shared formula
+func (c formulaContext) SetOffset(col, row uint32) {
+	c.Context.SetOffset(col, row)
+	if c.offset != nil {
+		*c.offset = cellOffset{col, row}
+	}
+}
+
+// Cell - result of the cell, unlike the gooxml sheet context it reads numbers in exponent
+// form, Cell.SetNumber stores 1e6 and more so ("1e+06")
+func (c formulaContext) Cell(ref string, ev formula.Evaluator) formula.Result {
+	res := c.Context.Cell(ref, ev)
+	if res.Type != formula.ResultTypeNumber || !math.IsNaN(res.ValueNumber) || !c.sheet.IsValid() {
+		return res
+	}
+	cr, err := reference.ParseCellReference(ref)
+	if err != nil {
+		return res
+	}
+	if c.offset != nil && !cr.AbsoluteColumn {
+		cr.ColumnIdx += c.offset.col
+		cr.Column = reference.IndexToColumn(cr.ColumnIdx)
+	}
+	if c.offset != nil && !cr.AbsoluteRow {
+		cr.RowIdx += c.offset.row
+	}
+	x := c.sheet.Cell(cr.String()).X()
+	if x.V == nil {
+		return res
+	}
+	if v, err := strconv.ParseFloat(*x.V, 64); err == nil {
+		return formula.MakeNumberResult(v)
+	}
+	return res
+}
+
+// NamedRange - reference of the defined name, a name holding something other than a
+// range is evaluated as a formula
+func (c formulaContext) NamedRange(name string) formula.Reference {
+	ref := c.Context.NamedRange(name)
+	if ref.Type == formula.ReferenceTypeRange && !strings.Contains(ref.Value, ":") {
+		// NamedRangeRef evaluates the value of a cell reference as a formula
+		return formula.Reference{Type: formula.ReferenceTypeCell, Value: strings.TrimPrefix(ref.Value, "=")}
+	}
+	return ref
+}
+
+// RecalculateFormulas - recompute cached results of formulas in all sheets of wb like
+// wb.RecalculateFormulas(), but in FormulaContext, so the spell formulas get their text.
+// If a formula gives an error, the error value is cached like Excel does: "#NUM!"
+func RecalculateFormulas(wb *spreadsheet.Workbook) {
+	for _, sheet := range wb.Sheets() {
+		RecalculateSheetFormulas(wb, sheet)
+	}
+}
+
+// RecalculateSheetFormulas - same as RecalculateFormulas for one sheet of wb
+func RecalculateSheetFormulas(wb *spreadsheet.Workbook, sheet spreadsheet.Sheet) {
+	ev := NewEvaluator()
+	ctx := FormulaContext(wb, sheet)
+	for _, row := range sheet.Rows() {
+		for _, cell := range row.Cells() {
+			f := cell.X().F
+			// cells of a shared formula are calculated with its first cell
+			if f == nil || f.TAttr == sml.ST_CellFormulaTypeShared && f.Content == "" {
+				continue
+			}
+			res := ev.Eval(ctx, f.Content)
+			setFormulaResult(cell, res)
+			switch {
+			case res.Type == formula.ResultTypeError:
+			case f.TAttr == sml.ST_CellFormulaTypeArray && res.Type == formula.ResultTypeArray:
+				setArrayResult(sheet, cell.Reference(), res)
+			case f.TAttr == sml.ST_CellFormulaTypeShared && f.RefAttr != nil:
+				from, to, err := reference.ParseRangeReference(*f.RefAttr)
+				if err != nil {
+					gooxml.Log("error in shared formula reference: %s", err)
+					continue
+				}
+				for r := from.RowIdx; r <= to.RowIdx; r++ {
+					for c := from.ColumnIdx; c <= to.ColumnIdx; c++ {
+						ctx.SetOffset(c-from.ColumnIdx, r-from.RowIdx)
+						ref := fmt.Sprintf("%s%d", reference.IndexToColumn(c), r)
+						setFormulaResult(sheet.Cell(ref), ev.Eval(ctx, f.Content))
+					}
+				}
+				ctx.SetOffset(0, 0)
+			}
+		}
+	}
+}
+
+// setFormulaResult - store res as the cached formula result of cell
+func setFormulaResult(cell spreadsheet.Cell, res formula.Result) {
+	if res.Type == formula.ResultTypeArray && len(res.ValueArray) > 0 && len(res.ValueArray[0]) > 0 {
+		res = res.ValueArray[0][0]
+	}
+	res = res.AsString()
+	switch res.Type {
+	case formula.ResultTypeError:
+		v := res.ValueString
+		if strings.HasPrefix(res.ErrorMessage, "#") {
+			// the gooxml engine evaluates error literals like #NUM! as #VALUE! with the
+			// literal as the message
+			v = res.ErrorMessage
+		} else {
+			gooxml.Log("error evaluating formula %s: %s", cell.GetFormula(), res.ErrorMessage)
+		}
+		cell.X().TAttr = sml.ST_CellTypeE
+		cell.X().V = gooxml.String(v)
+		return
+	case formula.ResultTypeNumber:
+		cell.X().TAttr = sml.ST_CellTypeN
+	default:
+		cell.X().TAttr = sml.ST_CellTypeStr
+	}
+	cell.X().V = gooxml.String(res.Value())
+}
+
+// setArrayResult - spread array result of the formula in origin cell to the cells below
+// and to the right of it
+func setArrayResult(sheet spreadsheet.Sheet, origin string, res formula.Result) {
+	cref, err := reference.ParseCellReference(origin)
+	if err != nil {
+		return
+	}
+	for ir, row := range res.ValueArray {
+		sr := sheet.Row(cref.RowIdx + uint32(ir))
+		for ic, v := range row {
+			sr.Cell(reference.IndexToColumn(cref.ColumnIdx + uint32(ic))).SetCachedFormulaResult(v.String())
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package gooxmlhelpers
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// spellAmounts - amounts spelled by the recalculation tests
+var spellAmounts = []float64{0, 1, 21.05, 112.5, -2000, 1001001.01, 2e12 + 5}
+
+func TestRecalculateFormulas(t *testing.T) {
+	tests := []struct {
+		name string
+		opts SpellOptions
+	}{
+		{"rub", SpellOptions{}},
+		{"usd", SpellOptions{Currency: USD}},
+		{"english eur", SpellOptions{Language: English, Currency: English.Currencies["EUR"]}},
+		{"ukrainian uah", SpellOptions{Language: Ukrainian, Currency: Ukrainian.Currencies["UAH"]}},
+		{"belarusian byn", SpellOptions{Language: Belarusian, Currency: Belarusian.Currencies["BYN"]}},
+		{"kazakh kzt", SpellOptions{Language: Kazakh, Currency: Kazakh.Currencies["KZT"]}},
+		{"minor words", SpellOptions{Minor: MinorWords, Case: CaseUpper}},
+		{"minor omitted", SpellOptions{Currency: CNY, Minor: MinorOmitted, Case: CaseLower}},
+		{"abbreviations", SpellOptions{Language: English, Currency: English.Currencies["USD"], AbbrMajor: true, AbbrMinor: true}},
+		{"parentheses", SpellOptions{Parentheses: true, Prefix: "GH"}},
+		{"genitive", SpellOptions{GramCase: Genitive, Minor: MinorWords}},
+		{"instrumental", SpellOptions{GramCase: Instrumental, Currency: USD}},
+	}
+	for _, tt := range tests {
+		wb := spreadsheet.New()
+		sheet := wb.AddSheet()
+		lang := tt.opts.language()
+		if err := SetDefinedNamesCase(wb, lang, tt.opts.Prefix, tt.opts.GramCase); err != nil {
+			t.Fatalf("%s: SetDefinedNamesCase: %s", tt.name, err)
+		}
+		for i, amount := range spellAmounts {
+			row := i + 1
+			sheet.Cell(fmt.Sprintf("A%d", row)).SetNumber(amount)
+			if err := SetSpellFormulaOptions(sheet.Cell(fmt.Sprintf("B%d", row)), fmt.Sprintf("A%d", row), tt.opts); err != nil {
+				t.Fatalf("%s: SetSpellFormulaOptions: %s", tt.name, err)
+			}
+		}
+		RecalculateFormulas(wb)
+		for i, amount := range spellAmounts {
+			got := sheet.Cell(fmt.Sprintf("B%d", i+1)).GetString()
+			if want := SpellAmountOptions(amount, tt.opts); got != want {
+				t.Errorf("%s: spell formula of %v = %q, want %q", tt.name, amount, got, want)
+			}
+		}
+	}
+}
+
+// spellValueOptions - options of the lines of testdata/spell_values.txt
+var spellValueOptions = map[string]SpellOptions{
+	"rub":           {},
+	"usd":           {Currency: USD},
+	"ukrainian uah": {Language: Ukrainian, Currency: Ukrainian.Currencies["UAH"]},
+	"english eur":   {Language: English, Currency: English.Currencies["EUR"]},
+	"genitive":      {GramCase: Genitive},
+}
+
+// spellValue - line of testdata/spell_values.txt
+type spellValue struct {
+	amount float64
+	text   string
+}
+
+// readSpellValues - expected texts of testdata/spell_values.txt by options
+func readSpellValues(t *testing.T) map[string][]spellValue {
+	f, err := os.Open("testdata/spell_values.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	values := map[string][]spellValue{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		if strings.HasPrefix(sc.Text(), "#") {
+			continue
+		}
+		parts := strings.Split(sc.Text(), "\t")
+		if len(parts) != 3 {
+			t.Fatalf("spell_values.txt:%d: want 3 fields, got %d", n, len(parts))
+		}
+		amount, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			t.Fatalf("spell_values.txt:%d: %s", n, err)
+		}
+		values[parts[0]] = append(values[parts[0]], spellValue{amount, parts[2]})
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestRecalculateFormulasPortable(t *testing.T) {
+	// kopecks of ABS(ref)*100 which aren't exact in floating point
+	amounts := append([]float64{0.29, 1.15, 0.57, 4.35, 1e12 + 0.07, -8.45}, spellAmounts...)
+	tests := []struct {
+		name string
+		opts SpellOptions
+	}{
+		{"rub", SpellOptions{Dialect: DialectPortable}},
+		{"english usd", SpellOptions{Language: English, Currency: English.Currencies["USD"], Dialect: DialectPortable}},
+		{"minor words", SpellOptions{Minor: MinorWords, Dialect: DialectPortable}},
+	}
+	for _, tt := range tests {
+		wb := spreadsheet.New()
+		sheet := wb.AddSheet()
+		for i, amount := range amounts {
+			sheet.Cell(fmt.Sprintf("A%d", i+1)).SetNumber(amount)
+			if err := SetSpellFormulaOptions(sheet.Cell(fmt.Sprintf("B%d", i+1)), fmt.Sprintf("A%d", i+1), tt.opts); err != nil {
+				t.Fatalf("%s: SetSpellFormulaOptions: %s", tt.name, err)
+			}
+		}
+		RecalculateFormulas(wb)
+		for i, amount := range amounts {
+			want := SpellAmountOptions(amount, tt.opts)
+			if tt.name == "rub" && want != SpellRub(amount) {
+				t.Errorf("SpellAmountOptions(%v) = %q, SpellRub gives %q", amount, want, SpellRub(amount))
+			}
+			if got := sheet.Cell(fmt.Sprintf("B%d", i+1)).GetString(); got != want {
+				t.Errorf("%s: portable spell formula of %v = %q, want %q", tt.name, amount, got, want)
+			}
+		}
+	}
+}
+
+func TestRecalculateFormulasExcelValues(t *testing.T) {
+	for name, values := range readSpellValues(t) {
+		opts, ok := spellValueOptions[name]
+		if !ok {
+			t.Fatalf("unknown options %q", name)
+		}
+		wb := spreadsheet.New()
+		sheet := wb.AddSheet()
+		if err := SetDefinedNamesCase(wb, opts.language(), opts.Prefix, opts.GramCase); err != nil {
+			t.Fatalf("%s: SetDefinedNamesCase: %s", name, err)
+		}
+		for i, v := range values {
+			sheet.Cell(fmt.Sprintf("A%d", i+1)).SetNumber(v.amount)
+			if err := SetSpellFormulaOptions(sheet.Cell(fmt.Sprintf("B%d", i+1)), fmt.Sprintf("A%d", i+1), opts); err != nil {
+				t.Fatalf("%s: SetSpellFormulaOptions: %s", name, err)
+			}
+		}
+		RecalculateFormulas(wb)
+		for i, v := range values {
+			if got := sheet.Cell(fmt.Sprintf("B%d", i+1)).GetString(); got != v.text {
+				t.Errorf("%s: spell formula of %v = %q, want %q", name, v.amount, got, v.text)
+			}
+			if got := SpellAmountOptions(v.amount, opts); got != v.text {
+				t.Errorf("%s: SpellAmountOptions(%v) = %q, want %q", name, v.amount, got, v.text)
+			}
+		}
+	}
+}
+
+func TestRecalculateFormulasShared(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	if err := SetDefinedNamesRub(wb); err != nil {
+		t.Fatal(err)
+	}
+	for i, amount := range spellAmounts {
+		sheet.Cell(fmt.Sprintf("A%d", i+2)).SetNumber(amount)
+	}
+	if err := SetSpellFormulaRange(sheet, fmt.Sprintf("B2:B%d", len(spellAmounts)+1), "A"); err != nil {
+		t.Fatal(err)
+	}
+	RecalculateFormulas(wb)
+	for i, amount := range spellAmounts {
+		if got, want := sheet.Cell(fmt.Sprintf("B%d", i+2)).GetString(), SpellRub(amount); got != want {
+			t.Errorf("shared formula of %v = %q, want %q", amount, got, want)
+		}
+	}
+}
+
+func TestSetSpellValueOptions(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	opts := SpellOptions{Language: English, Currency: USD, Minor: MinorWords}
+	for i, amount := range spellAmounts {
+		ref := fmt.Sprintf("A%d", i+1)
+		sheet.Cell(ref).SetNumber(amount)
+		cell := sheet.Cell(fmt.Sprintf("B%d", i+1))
+		if err := SetSpellValueOptions(sheet, cell, ref, opts); err != nil {
+			t.Fatalf("SetSpellValueOptions(%v): %s", amount, err)
+		}
+		if got, want := cell.GetString(), SpellAmountOptions(amount, opts); got != want {
+			t.Errorf("cached value of %v = %q, want %q", amount, got, want)
+		}
+	}
+	sheet.Cell("C1").SetString("text")
+	if err := SetSpellValueOptions(sheet, sheet.Cell("D1"), "C1", opts); err == nil {
+		t.Error("text is spelled")
+	}
+}
@@ -0,0 +1,135 @@
+package gooxmlhelpers
+
+import (
+	"errors"
+	"fmt"
+
+	"baliance.com/gooxml"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// FillSpec - fill of a cell: a pattern (sml.ST_PatternTypeDarkGray, ...) of FgColor on
+// BgColor or a gradient when Gradient is set. The pattern is solid when it isn't set, a
+// solid fill has FgColor only. A zero Color isn't written
+type FillSpec struct {
+	Pattern  sml.ST_PatternType
+	FgColor  Color
+	BgColor  Color
+	Gradient *GradientSpec
+}
+
+// GradientSpec - gradient fill: linear at angle Degree (0 is from left to right, 90 from
+// top to bottom) or, when Path is set, from the rectangle given by Left, Right, Top and
+// Bottom (0-1, fractions of the cell) to the borders of the cell
+type GradientSpec struct {
+	Path                     bool
+	Degree                   float64
+	Left, Right, Top, Bottom float64
+	Stops                    []GradientStop
+}
+
+// GradientStop - color of a gradient at Position, 0 is the start and 1 is the end
+type GradientStop struct {
+	Position float64
+	Color    Color
+}
+
+// SolidFill - fill spec of solid color clr
+func SolidFill(clr Color) FillSpec {
+	return FillSpec{Pattern: sml.ST_PatternTypeSolid, FgColor: clr}
+}
+
+// check - check that Excel can render the fill
+func (s FillSpec) check() error {
+	g := s.Gradient
+	if g == nil {
+		return nil
+	}
+	if s.Pattern != sml.ST_PatternTypeUnset {
+		return errors.New("fill can't have both pattern and gradient")
+	}
+	if len(g.Stops) < 2 {
+		return fmt.Errorf("gradient has %d stops, at least 2 are needed", len(g.Stops))
+	}
+	for i, st := range g.Stops {
+		if st.Position < 0 || st.Position > 1 || i > 0 && st.Position < g.Stops[i-1].Position {
+			return fmt.Errorf("gradient stop %d at %g: positions must go up from 0 to 1", i, st.Position)
+		}
+		if st.Color.IsZero() {
+			return fmt.Errorf("gradient stop %d at %g has no color", i, st.Position)
+		}
+	}
+	for _, v := range []float64{g.Left, g.Right, g.Top, g.Bottom} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("gradient convergence %g isn't from 0 to 1", v)
+		}
+	}
+	return nil
+}
+
+// fill - fill of the stylesheet
+func (s FillSpec) fill() *sml.CT_Fill {
+	f := sml.NewCT_Fill()
+	if g := s.Gradient; g != nil {
+		f.GradientFill = sml.NewCT_GradientFill()
+		gf := f.GradientFill
+		if g.Path {
+			gf.TypeAttr = sml.ST_GradientTypePath
+			for _, a := range []struct {
+				attr **float64
+				v    float64
+			}{{&gf.LeftAttr, g.Left}, {&gf.RightAttr, g.Right}, {&gf.TopAttr, g.Top}, {&gf.BottomAttr, g.Bottom}} {
+				if a.v != 0 {
+					*a.attr = gooxml.Float64(a.v)
+				}
+			}
+		} else if g.Degree != 0 {
+			gf.DegreeAttr = gooxml.Float64(g.Degree)
+		}
+		for _, st := range g.Stops {
+			gf.Stop = append(gf.Stop, &sml.CT_GradientStop{PositionAttr: st.Position, Color: st.Color.x()})
+		}
+		return f
+	}
+	f.PatternFill = sml.NewCT_PatternFill()
+	f.PatternFill.PatternTypeAttr = s.Pattern
+	if s.Pattern == sml.ST_PatternTypeUnset {
+		f.PatternFill.PatternTypeAttr = sml.ST_PatternTypeSolid
+	}
+	f.PatternFill.FgColor = s.FgColor.x()
+	f.PatternFill.BgColor = s.BgColor.x()
+	return f
+}
+
+// SetFill - fill the cell as spec says, an invalid spec fails the change of the style
+func (d *StyleDelta) SetFill(spec FillSpec) {
+	if err := spec.check(); err != nil {
+		d.setErr(err)
+		return
+	}
+	d.fill = spec.fill()
+}
+
+// FillCell - fill cell as spec says keeping the other attributes of its style like
+// FillColor, returns the style index of the cell. An error is returned for an invalid
+// spec and a broken stylesheet, see ModifyCellStyleChecked
+func FillCell(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, spec FillSpec) (uint32, error) {
+	if err := spec.check(); err != nil {
+		return 0, err
+	}
+	return ModifyCellStyleChecked(ss, cell, func(d *StyleDelta) {
+		d.SetFill(spec)
+	})
+}
+
+// FillRange - fill the cells of range ref of sheet as spec says, see FillCell and
+// ModifyRangeStyle
+func FillRange(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet, ref string, spec FillSpec) error {
+	if err := spec.check(); err != nil {
+		return err
+	}
+	return ModifyRangeStyle(ss, sheet, ref, func(d *StyleDelta) {
+		d.SetFill(spec)
+	})
+}
@@ -0,0 +1,117 @@
+package gooxmlhelpers
+
+import (
+	"strings"
+	"testing"
+
+	"baliance.com/gooxml/color"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestFillSpecCheck(t *testing.T) {
+	red, blue := RGBColor(color.Red), RGBColor(color.Blue)
+	tests := []struct {
+		spec FillSpec
+		err  string
+	}{
+		{SolidFill(red), ""},
+		{FillSpec{Gradient: &GradientSpec{Stops: []GradientStop{{0, red}, {1, blue}}}}, ""},
+		{FillSpec{Gradient: &GradientSpec{Path: true, Left: 0.5, Right: 0.5, Stops: []GradientStop{{0, red}, {0.5, ThemeColor(4, 0.4)}, {1, blue}}}}, ""},
+		{FillSpec{Pattern: sml.ST_PatternTypeSolid, Gradient: &GradientSpec{Stops: []GradientStop{{0, red}, {1, blue}}}}, "both pattern and gradient"},
+		{FillSpec{Gradient: &GradientSpec{Stops: []GradientStop{{0, red}}}}, "1 stops"},
+		{FillSpec{Gradient: &GradientSpec{Stops: []GradientStop{{0.5, red}, {0.2, blue}}}}, "positions must go up"},
+		{FillSpec{Gradient: &GradientSpec{Stops: []GradientStop{{0, red}, {1, Color{}}}}}, "stop 1 at 1 has no color"},
+		{FillSpec{Gradient: &GradientSpec{Stops: []GradientStop{{0, Color{}}, {1, blue}}}}, "stop 0 at 0 has no color"},
+		{FillSpec{Gradient: &GradientSpec{Path: true, Top: 1.5, Stops: []GradientStop{{0, red}, {1, blue}}}}, "convergence 1.5"},
+	}
+	for i, tt := range tests {
+		err := tt.spec.check()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%d: unexpected error: %s", i, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%d: error %v, want %q", i, err, tt.err)
+		}
+	}
+
+	wb := spreadsheet.New()
+	cell := wb.AddSheet().Cell("A1")
+	spec := FillSpec{Gradient: &GradientSpec{Stops: []GradientStop{{0, red}, {1, Color{}}}}}
+	if _, err := FillCell(wb.StyleSheet, cell, spec); err == nil {
+		t.Error("FillCell accepted a gradient stop without color")
+	}
+	if cell.X().SAttr != nil {
+		t.Errorf("FillCell changed the cell style to %d", *cell.X().SAttr)
+	}
+}
+
+// cellFill - fill of the style of cell
+func cellFill(t *testing.T, ss spreadsheet.StyleSheet, cell spreadsheet.Cell) *sml.CT_Fill {
+	t.Helper()
+	xf, _, _ := cellStyle(t, ss, cell)
+	if xf.FillIdAttr == nil {
+		return ss.X().Fills.Fill[0]
+	}
+	return ss.X().Fills.Fill[*xf.FillIdAttr]
+}
+
+func TestFillRange(t *testing.T) {
+	red, blue := RGBColor(color.Red), RGBColor(color.Blue)
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("a")
+	spec := FillSpec{Gradient: &GradientSpec{Degree: 90, Stops: []GradientStop{{0, red}, {1, blue}}}}
+	if err := FillRange(wb.StyleSheet, sheet, "A1:B2", spec); err != nil {
+		t.Fatal(err)
+	}
+	for _, ref := range []string{"A1", "A2", "B1", "B2"} {
+		f := cellFill(t, wb.StyleSheet, sheet.Cell(ref))
+		if f == nil || f.GradientFill == nil || len(f.GradientFill.Stop) != 2 {
+			t.Errorf("%s isn't filled with the gradient", ref)
+		}
+	}
+
+	bad := FillSpec{Gradient: &GradientSpec{Stops: []GradientStop{{0, red}}}}
+	if err := FillRange(wb.StyleSheet, sheet, "C1:C2", bad); err == nil {
+		t.Error("FillRange accepted a gradient with 1 stop")
+	}
+	for _, ref := range []string{"C1", "C2"} {
+		if c := sheet.Cell(ref); c.X().SAttr != nil {
+			t.Errorf("FillRange changed the style of %s to %d", ref, *c.X().SAttr)
+		}
+	}
+}
+
+func TestStyleDeltaSetFill(t *testing.T) {
+	red, blue := RGBColor(color.Red), RGBColor(color.Blue)
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	ss := wb.StyleSheet
+	cell := sheet.Cell("A1")
+	spec := FillSpec{Pattern: sml.ST_PatternTypeDarkGray, FgColor: red, BgColor: blue}
+	if s := ModifyCellStyle(ss, cell, func(d *StyleDelta) { d.SetFill(spec) }); s == 0 {
+		t.Fatal("ModifyCellStyle didn't change the style")
+	}
+	f := cellFill(t, ss, cell)
+	if f == nil || f.PatternFill == nil || f.PatternFill.PatternTypeAttr != sml.ST_PatternTypeDarkGray {
+		t.Fatalf("fill %+v, want a dark gray pattern", f)
+	}
+
+	styles := len(ss.X().CellXfs.Xf)
+	bad := FillSpec{Gradient: &GradientSpec{Stops: []GradientStop{{0, red}, {1, Color{}}}}}
+	setBad := func(d *StyleDelta) {
+		d.SetBold(true)
+		d.SetFill(bad)
+	}
+	before := *cell.X().SAttr
+	if _, err := ModifyCellStyleChecked(ss, cell, setBad); err == nil || !strings.Contains(err.Error(), "has no color") {
+		t.Errorf("ModifyCellStyleChecked error %v, want the gradient one", err)
+	}
+	if s := ModifyCellStyle(ss, cell, setBad); s != 0 {
+		t.Errorf("ModifyCellStyle gives style %d, want 0", s)
+	}
+	if *cell.X().SAttr != before || len(ss.X().CellXfs.Xf) != styles {
+		t.Errorf("the invalid fill changed the cell style to %d of %d styles", *cell.X().SAttr, len(ss.X().CellXfs.Xf))
+	}
+}
@@ -0,0 +1,73 @@
+package gooxmlhelpers
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenFormulas - spell formulas of every language for A1 kept in the golden files
+func goldenFormulas(d Dialect) string {
+	tests := []struct {
+		name string
+		opts SpellOptions
+	}{
+		{"ru RUB", SpellOptions{}},
+		{"ru USD minor words", SpellOptions{Currency: USD, Minor: MinorWords}},
+		{"ru EUR parentheses upper", SpellOptions{Currency: EUR, Parentheses: true, Case: CaseUpper}},
+		{"en USD", SpellOptions{Language: English, Currency: USD}},
+		{"en CNY minor omitted", SpellOptions{Language: English, Currency: CNY, Minor: MinorOmitted}},
+		{"uk UAH", SpellOptions{Language: Ukrainian, Currency: UAH}},
+		{"be BYN abbreviations", SpellOptions{Language: Belarusian, Currency: BYN, AbbrMajor: true, AbbrMinor: true}},
+		{"kk KZT prefix", SpellOptions{Language: Kazakh, Currency: KZT, Prefix: "GH"}},
+	}
+	var sb strings.Builder
+	for _, tt := range tests {
+		tt.opts.Dialect = d
+		f, err := GetSpellFormulaOptions("A1", tt.opts)
+		if err != nil {
+			f = "error: " + err.Error()
+		}
+		sb.WriteString("# " + tt.name + "\n" + f + "\n")
+	}
+	return sb.String()
+}
+
+func TestSpellFormulaGolden(t *testing.T) {
+	for _, tt := range []struct {
+		file    string
+		dialect Dialect
+	}{
+		{"spell_excel.golden", DialectExcel},
+		{"spell_portable.golden", DialectPortable},
+	} {
+		path := filepath.Join("testdata", tt.file)
+		got := goldenFormulas(tt.dialect)
+		if *update {
+			if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			gotLines, wantLines := strings.Split(got, "\n"), strings.Split(string(want), "\n")
+			for i := range gotLines {
+				if i >= len(wantLines) || gotLines[i] != wantLines[i] {
+					t.Errorf("%s differs at line %d, run go test -update if the change is intended", tt.file, i+1)
+					break
+				}
+			}
+			if len(gotLines) < len(wantLines) {
+				t.Errorf("%s has %d lines, want %d", tt.file, len(gotLines), len(wantLines))
+			}
+		}
+	}
+}
@@ -1,99 +1,728 @@
 package gooxmlhelpers // import "github.com/l0rda/gooxmlhelpers"
 
 import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 
-	"baliance.com/gooxml/color"
-	"baliance.com/gooxml/schema/soo/sml"
 	"baliance.com/gooxml/spreadsheet"
+	"baliance.com/gooxml/spreadsheet/formula"
 )
 
 // Formula originally was posted at http://www.excelworld.ru/forum/3-9902-1
-// by MCH (http://www.excelworld.ru/index/8-41)
-const excelNumToTextFormula = `=SUBSTITUTE(PROPER(INDEX(n_4,MID(TEXT(A1,n0),1,1)+1)&INDEX(n0x,MID(TEXT(A1,n0),2,1)+1,MID(TEXT(A1,n0),3,1)+1)&IF(-MID(TEXT(A1,n0),1,3),"миллиард"&VLOOKUP(MID(TEXT(A1,n0),3,1)*AND(MID(TEXT(A1,n0),2,1)-1),mil,2),"")&INDEX(n_4,MID(TEXT(A1,n0),4,1)+1)&INDEX(n0x,MID(TEXT(A1,n0),5,1)+1,MID(TEXT(A1,n0),6,1)+1)&IF(-MID(TEXT(A1,n0),4,3),"миллион"&VLOOKUP(MID(TEXT(A1,n0),6,1)*AND(MID(TEXT(A1,n0),5,1)-1),mil,2),"")&INDEX(n_4,MID(TEXT(A1,n0),7,1)+1)&INDEX(n1x,MID(TEXT(A1,n0),8,1)+1,MID(TEXT(A1,n0),9,1)+1)&IF(-MID(TEXT(A1,n0),7,3),VLOOKUP(MID(TEXT(A1,n0),9,1)*AND(MID(TEXT(A1,n0),8,1)-1),ths,2),"")&INDEX(n_4,MID(TEXT(A1,n0),10,1)+1)&INDEX(n0x,MID(TEXT(A1,n0),11,1)+1,MID(TEXT(A1,n0),12,1)+1)),"z"," ")&IF(TRUNC(TEXT(A1,n0)),"","Ноль ")&"рубл"&VLOOKUP(MOD(MAX(MOD(MID(TEXT(A1,n0),11,2)-11,100),9),10),{0,"ь ";1,"я ";4,"ей "},2)&RIGHT(TEXT(A1,n0),2)&" копе"&VLOOKUP(MOD(MAX(MOD(RIGHT(TEXT(A1,n0),2)-11,100),9),10),{0,"йка";1,"йки";4,"ек"},2)`
+// by MCH (http://www.excelworld.ru/index/8-41), now it's built for every language by
+// spellFormula. Words are kept in defined names with spaces replaced by "z", so PROPER
+// capitalizes only the first letter of the text, then SUBSTITUTE puts spaces back.
+// The absolute value is padded to 15 digits by N_0 format and spelled by groups of three
+// digits, the minus word is added for negative numbers
 
-var defNames = map[string]string{
-	"n_1": `{"","одинz","дваz","триz","четыреz","пятьz","шестьz","семьz","восемьz","девятьz"}`,
-	"n_2": `{"десятьz","одиннадцатьz","двенадцатьz","тринадцатьz","четырнадцатьz","пятнадцатьz","шестнадцатьz","семнадцатьz","восемнадцатьz","девятнадцатьz"}`,
-	"n_3": `{"";1;"двадцатьz";"тридцатьz";"сорокz";"пятьдесятz";"шестьдесятz";"семьдесятz";"восемьдесятz";"девяностоz"}`,
-	"n_4": `{"","стоz","двестиz","тристаz","четырестаz","пятьсотz","шестьсотz","семьсотz","восемьсотz","девятьсотz"}`,
-	"n_5": `{"","однаz","двеz","триz","четыреz","пятьz","шестьz","семьz","восемьz","девятьz"}`,
-	"n0":  `"000000000000"&MID(1/2,2,1)&"00"`,
-	"n0x": `IF(n_3=1,n_2,n_3&n_1)`,
-	"n1x": `IF(n_3=1,n_2,n_3&n_5)`,
-	"mil": `{0,"овz";1,"z";2,"аz";5,"овz"}`,
-	"ths": `{0,"тысячz";1,"тысячаz";2,"тысячиz";5,"тысячz"}`,
+// spellDigits is the number of integer digits in the N_0 format of spell formulas
+const spellDigits = 15
+
+// wordPlaceholders - letters standing for non-letter characters of the words in defined
+// names, they must not be used in the vocabulary
+var wordPlaceholders = []struct{ char, letter string }{
+	{" ", "z"},
+	{"-", "q"},
+	{"'", "j"},
+}
+
+// SetDefinedNamesRub - set defined names for GetSpellFormula and GetSpellFormulaCurrency
+func SetDefinedNamesRub(wb *spreadsheet.Workbook) error {
+	return SetDefinedNamesPrefix(wb, Russian, "")
 }
 
-// SetDefinedNamesRub - set defined names for SetNum2RubFormula
-func SetDefinedNamesRub(wb *spreadsheet.Workbook) {
-	for k, v := range defNames {
-		wb.AddDefinedName(k, v)
+// SetDefinedNamesLanguage - set defined names for GetSpellFormulaLanguage, names of every
+// language but Russian are prefixed with the language code ("EN_N_0")
+func SetDefinedNamesLanguage(wb *spreadsheet.Workbook, lang *Language) error {
+	return SetDefinedNamesPrefix(wb, lang, "")
+}
+
+// SetDefinedNamesPrefix - set defined names for GetSpellFormulaPrefix with the same prefix
+// ("GH_N_0", prefix is made upper case). Names already defined in the workbook with the same
+// content are skipped, so it's safe to call it again. If a name is defined with other
+// content, an error is returned and no names are added.
+// The names N0, N0X and N1X of the older versions are renamed to N_0, N_0X and N_1X, as
+// the gooxml formula parser reads N0 as a cell reference. If the workbook has the old
+// names, they are removed when no formula of the cells and no other name uses them,
+// otherwise they are kept, so the formulas set by the older versions keep working
+func SetDefinedNamesPrefix(wb *spreadsheet.Workbook, lang *Language, prefix string) error {
+	if !validNamePrefix(prefix) {
+		return fmt.Errorf("invalid defined name prefix %q", prefix)
+	}
+	if err := addDefinedNames(wb, lang.definedNames(prefix)); err != nil {
+		return err
+	}
+	replaceLegacyNames(wb, namePrefix(prefix, lang))
+	return nil
+}
+
+// legacyNames - names of the vocabulary set by the older versions: N0, N0X and N1X, the
+// word arrays N0X and N1X were built of and the million endings of the first version
+var legacyNames = []string{"N0", "N0X", "N1X", "N_1", "N_2", "N_3", "N_5", "N_6", "MIL"}
+
+// legacyN0 - content of the N0 name set by the older versions, 12 integer digits
+const legacyN0 = `"000000000000"&MID(1/2,2,1)&"00"`
+
+// replaceLegacyNames - remove workbook scope legacyNames with prefix p, if p+"N0" is the
+// name set by the older versions and the legacy names aren't used by the formulas of the
+// cells or by other names
+func replaceLegacyNames(wb *spreadsheet.Workbook, p string) {
+	found := map[string]spreadsheet.DefinedName{}
+	for _, dn := range wb.DefinedNames() {
+		if dn.X().LocalSheetIdAttr == nil {
+			found[strings.ToUpper(dn.Name())] = dn
+		}
+	}
+	n0, ok := found[strings.ToUpper(p+"N0")]
+	if !ok || !strings.EqualFold(strings.TrimPrefix(n0.Content(), "="), legacyN0) {
+		return
+	}
+	legacy := map[string]bool{}
+	for _, name := range legacyNames {
+		legacy[strings.ToUpper(p+name)] = true
+	}
+	for _, dn := range wb.DefinedNames() {
+		if !legacy[strings.ToUpper(dn.Name())] && usesNames(dn.Content(), legacy) {
+			return
+		}
+	}
+	for _, sheet := range wb.Sheets() {
+		for _, row := range sheet.Rows() {
+			for _, cell := range row.Cells() {
+				if f := cell.X().F; f != nil && usesNames(f.Content, legacy) {
+					return
+				}
+			}
+		}
+	}
+	for name := range legacy {
+		if dn, ok := found[name]; ok {
+			wb.RemoveDefinedName(dn)
+		}
 	}
 }
 
-// GetSpellFormula - return num2spell formula for cell (Russian rubles)
+// usesNames - check if formula s has one of upper case names outside of literals
+func usesNames(s string, names map[string]bool) bool {
+	var quote rune
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case isNameChar(r) && (i == 0 || !isNameChar(rune(s[i-1]))):
+			j := i
+			for j < len(s) && isNameChar(rune(s[j])) {
+				j++
+			}
+			if names[strings.ToUpper(s[i:j])] {
+				return true
+			}
+			i = j
+			continue
+		}
+		i++
+	}
+	return false
+}
+
+// addDefinedNames - add workbook scope names which aren't defined yet, if a name is
+// defined with other content, an error is returned and no names are added
+func addDefinedNames(wb *spreadsheet.Workbook, names map[string]string) error {
+	existing := map[string]string{}
+	for _, dn := range wb.DefinedNames() {
+		if dn.X().LocalSheetIdAttr != nil {
+			// sheet scope names don't clash with workbook scope ones
+			continue
+		}
+		// names are case-insensitive
+		existing[strings.ToLower(dn.Name())] = strings.TrimPrefix(dn.Content(), "=")
+	}
+	keys := make([]string, 0, len(names))
+	for k, v := range names {
+		content, ok := existing[strings.ToLower(k)]
+		if !ok {
+			keys = append(keys, k)
+			continue
+		}
+		// the words case doesn't matter as PROPER fixes it
+		if !strings.EqualFold(content, v) {
+			return fmt.Errorf("defined name %s already exists with other content %q", k, content)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		wb.AddDefinedName(k, names[k])
+	}
+	return nil
+}
+
+// validNamePrefix - check that prefix can start a defined name parsed by the gooxml formula
+// parser: latin letters, digits, underscores and dots, starting with a letter or underscore
+func validNamePrefix(prefix string) bool {
+	for i, r := range prefix {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case i > 0 && (r >= '0' && r <= '9' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// namePrefix - prefix of the defined names for language, the gooxml formula parser
+// only knows names starting with upper case letter, so it's always upper case
+func namePrefix(prefix string, lang *Language) string {
+	return strings.ToUpper(prefix) + lang.prefix
+}
+
+// GetSpellFormula - return num2spell formula for cell (Russian rubles). ref isn't checked,
+// as it wasn't before the other helpers got errors: an invalid ref gives a broken formula.
+//
+// Deprecated: use GetSpellFormulaCurrency, which returns an error for an invalid ref
 func GetSpellFormula(ref string) string {
-	return strings.Replace(excelNumToTextFormula, "A1", ref, 25)
+	return spellFormula(ref, SpellOptions{})
 }
 
-// SetSpellFormula - convert ref cell value(number) to words (Russian rubles), you need to run SetDefinedNamesRub()
-func SetSpellFormula(cell spreadsheet.Cell, ref string) {
-	cell.SetFormulaRaw(GetSpellFormula(ref))
-}
-
-// FillColor - fill cell by reference with color and save current cell style
-func FillColor(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, clr color.Color) {
-	origStylePointer := cell.X().SAttr
-	cs := ss.AddCellStyle()
-	fills := ss.Fills()
-	f := fills.AddFill()
-	f.SetPatternFill().SetFgColor(clr)
-	fillID := f.Index()
-	if origStylePointer == nil {
-		// don't need to copy style
-		cs.SetFill(f)
-		cell.SetStyle(cs)
-		return
+// GetSpellFormulaCurrency - return num2spell formula for cell in given currency,
+// you need to run SetDefinedNamesRub(), the currency names are inlined in the formula
+func GetSpellFormulaCurrency(ref string, cur Currency) (string, error) {
+	return GetSpellFormulaLanguage(ref, Russian, cur)
+}
+
+// GetSpellFormulaLanguage - return num2spell formula for cell in given language and
+// currency, you need to run SetDefinedNamesLanguage() for the language
+func GetSpellFormulaLanguage(ref string, lang *Language, cur Currency) (string, error) {
+	return GetSpellFormulaPrefix(ref, lang, cur, "")
+}
+
+// GetSpellFormulaPrefix - same as GetSpellFormulaLanguage, but uses defined names set by
+// SetDefinedNamesPrefix with the same prefix
+func GetSpellFormulaPrefix(ref string, lang *Language, cur Currency, prefix string) (string, error) {
+	return GetSpellFormulaOptions(ref, SpellOptions{Language: lang, Currency: cur, Prefix: prefix})
+}
+
+// GetSpellFormulaOptions - return num2spell formula for cell in the language, currency and
+// style set by opts, the defined names must be set for the language and opts.Prefix
+// unless the formula is built in DialectPortable. ref can be any expression giving a
+// number: a cell ("$B$5"), a cell of another sheet ("'Итоги 2026'!$B$5"), a defined
+// name ("Total") or a calculation ("B5*1.2"). It is checked by the gooxml formula parser
+// and put only where the formula takes the number, as well as the whole formula is parsed
+// before it's returned. The formula rounds the number to kopecks, supports absolute values
+// up to 999 999 999 999 999.99 and gives the #NUM! error for bigger ones, so that a wrong
+// amount is never spelled. A formula longer than Excel allows gives ErrFormulaTooLong, it
+// isn't shortened automatically: use GetSpellFormulaIndirect for long references. For
+// opts.GramCase other than Nominative the names are set by SetDefinedNamesCase
+func GetSpellFormulaOptions(ref string, opts SpellOptions) (string, error) {
+	ref, err := checkSpellRef(ref, opts.Prefix)
+	if err != nil {
+		return "", err
+	}
+	if err := opts.language().checkCase(opts.GramCase, opts.currency()); err != nil {
+		return "", err
+	}
+	f := spellFormula(ref, opts)
+	if err := checkFormula(f); err != nil {
+		return "", fmt.Errorf("can't build spell formula for %q: %s", ref, err)
+	}
+	if err := checkFormulaLen(f); err != nil {
+		return "", fmt.Errorf("can't build spell formula for %q: %w", ref, err)
+	}
+	return f, nil
+}
+
+// checkSpellRef - check reference and defined names prefix passed to a spell formula,
+// returns the reference as it's put into the formula, see parseSpellRef
+func checkSpellRef(ref, prefix string) (string, error) {
+	if !validNamePrefix(prefix) {
+		return "", fmt.Errorf("invalid defined name prefix %q", prefix)
+	}
+	if strings.TrimSpace(ref) == "" {
+		return "", errors.New("empty reference")
+	}
+	if strings.HasPrefix(ref, "=") {
+		return "", fmt.Errorf("invalid reference %q: must not start with =", ref)
+	}
+	text, err := parseSpellRef(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid reference %q: %s", ref, err)
+	}
+	return text, nil
+}
+
+// parseSpellRef - parse ref as a single expression giving one value. A cell or a name is
+// put into the formula as the parser read it, other expressions are put as they are
+func parseSpellRef(ref string) (string, error) {
+	expr, err := parseExpression(ref)
+	if err != nil {
+		return "", err
+	}
+	switch e := expr.(type) {
+	case formula.EmptyExpr, formula.Range, *formula.ConstArrayExpr:
+		return "", errors.New("must be a single value, not a range or an array")
+	case formula.CellRef, formula.NamedRangeRef:
+		// the reference of a cell or a name doesn't depend on the context
+		return e.Reference(nil, nil).Value, nil
+	}
+	return ref, nil
+}
+
+// parseExpression - parse s as a single expression by the gooxml formula parser. It returns
+// the parsed beginning of a malformed formula ("A1" of "A1,B1"), so s is also parsed in
+// brackets compared with zero: the left side of the comparison must be the whole of s
+func parseExpression(s string) (formula.Expression, error) {
+	expr := formula.ParseString(s)
+	if expr == nil {
+		return nil, errors.New("parse error")
+	}
+	whole := formula.ParseString("(" + s + ")=0")
+	if !reflect.DeepEqual(whole, formula.NewBinaryExpr(expr, formula.BinOpTypeEQ, formula.NewNumber("0"))) {
+		return nil, errors.New("not a single expression")
+	}
+	return expr, nil
+}
+
+// checkFormula - check that formula f starting with = is a single expression
+func checkFormula(f string) error {
+	_, err := parseExpression(strings.TrimPrefix(f, "="))
+	return err
+}
+
+// spellFormula - build num2spell formula for ref
+func spellFormula(ref string, opts SpellOptions) string {
+	lang, cur := opts.language(), opts.currency()
+	b := newSpellBuilder(ref, 2, opts)
+	neg := b.negative()
+	text := b.integer(neg, func(t, u string) string { return b.tens(cur.Major.Gender, t, u) }, opts.Case)
+	if opts.Parentheses {
+		// TEXT keeps all digits, which are lost when a big number is joined to text
+		text = fmt.Sprintf(`IF(%s,"-","")&TEXT(%s,"0")&" ("&TRIM(%s)&") "`, neg, b.whole(), text)
+	}
+	parts := []string{
+		text,
+		b.unit(cur.Major, opts.AbbrMajor, b.mid(spellDigits-1, 2), b.mid(spellDigits-2, 3), b.whole()),
+	}
+	cents := "RIGHT(" + b.t + ",2)"
+	switch opts.Minor {
+	case MinorDigits:
+		parts = append(parts, `" "`, cents)
+	case MinorWords:
+		minor := lang.decodeWords(b.tens(cur.Minor.Gender, b.decimal(1, 1), b.decimal(2, 1)))
+		parts = append(parts, `" "`, fmt.Sprintf(`TRIM(%s)&IF(VALUE(%s),"",%s)`, minor, cents, formulaString(b.w.zero)))
+	}
+	if opts.Minor != MinorOmitted {
+		value := "VALUE(" + cents + ")"
+		parts = append(parts, `" "`, b.unit(cur.Minor, opts.AbbrMinor, value, value, value))
+	}
+	return "=" + b.rangeCheck(opts.Case.wrap(strings.Join(parts, "&")))
+}
+
+// spellBuilder - builds parts of the spell formulas for the absolute value of ref rounded
+// and padded by zeros to text t, the words are taken in the dialect of the options
+type spellBuilder struct {
+	lang *Language
+	p    string
+	// gc - grammatical case of the words, w - the words in this case and s - suffix of
+	// their defined names
+	gc       GrammaticalCase
+	w        caseWords
+	s        string
+	portable bool
+	ref      string
+	t        string
+	// point - position of the first decimal digit in t
+	point    int
+	decimals int
+}
+
+// newSpellBuilder - builder for ref rounded to decimals. Excel dialect pads the number by
+// N_0 format with the decimal separator of the locale, portable one formats the number
+// multiplied by 10^decimals, so there is no separator at all
+func newSpellBuilder(ref string, decimals int, opts SpellOptions) *spellBuilder {
+	lang := opts.language()
+	b := &spellBuilder{
+		lang:     lang,
+		p:        namePrefix(opts.Prefix, lang),
+		gc:       opts.GramCase,
+		s:        opts.GramCase.nameSuffix(),
+		portable: opts.Dialect == DialectPortable,
+		ref:      ref,
+		decimals: decimals,
+	}
+	b.w, _ = lang.words(opts.GramCase)
+	if b.portable {
+		b.t = fmt.Sprintf(`TEXT(ABS(%s)*1%s,"%s")`, ref, strings.Repeat("0", decimals), strings.Repeat("0", spellDigits+decimals))
+		b.point = spellDigits + 1
+		return b
+	}
+	b.t = "TEXT(ABS(" + ref + ")," + b.p + "N_0"
+	if decimals > 2 {
+		b.t += "&" + formulaString(strings.Repeat("0", decimals-2))
+	}
+	b.t += ")"
+	b.point = spellDigits + 2
+	return b
+}
+
+// mid - formula of n digits of t from start as a number, the gooxml engine doesn't do
+// arithmetic on text
+func (b *spellBuilder) mid(start, n int) string {
+	return fmt.Sprintf("VALUE(MID(%s,%d,%d))", b.t, start, n)
+}
+
+// decimal - formula of n decimal digits from i-th one
+func (b *spellBuilder) decimal(i, n int) string {
+	return b.mid(b.point+i-1, n)
+}
+
+// whole - formula of the integer part of the number
+func (b *spellBuilder) whole() string {
+	if b.portable {
+		return b.mid(1, spellDigits)
 	}
-	var copySrc *sml.CT_Xf
-	for i, doc := range ss.X().CellXfs.Xf {
-		if uint32(i) == *origStylePointer {
-			copySrc = doc
+	return "TRUNC(" + b.t + ")"
+}
+
+// negative - formula checking that ref is negative and not rounded to zero
+func (b *spellBuilder) negative() string {
+	return fmt.Sprintf("AND(SIGN(%s)<0,VALUE(%s))", b.ref, b.t)
+}
+
+// rangeCheck - wrap formula f to give #NUM! when the rounded number has more than
+// spellDigits integer digits
+func (b *spellBuilder) rangeCheck(f string) string {
+	return fmt.Sprintf("IF(LEN(%s)>%d,#NUM!,%s)", b.t, b.point+b.decimals-1, f)
+}
+
+// integer - formula of the words for the integer part of the number with the minus word
+// when neg is true, zero included. tens gives the words for the last two digits
+func (b *spellBuilder) integer(neg string, tens func(t, u string) string, c LetterCase) string {
+	l := b.lang
+	zero := b.w.zero
+	if c == CaseFirst {
+		zero = capitalize(zero)
+	}
+	// minus is joined to the number with the space placeholder, so PROPER capitalizes
+	// only the first word
+	return l.decodeWords(fmt.Sprintf(`PROPER(IF(%s,%s,"")&%s)`, neg, formulaString(encodeWord(l.minus)+"z"), b.number(tens))) +
+		fmt.Sprintf(`&IF(%s,"",IF(%s,%s,%s))`, b.whole(), neg, formulaString(b.w.zero+" "), formulaString(zero+" "))
+}
+
+// number - formula of the words for the integer part of the number (spellDigits digits),
+// tens gives the words for the last two digits
+func (b *spellBuilder) number(tens func(t, u string) string) string {
+	l := b.lang
+	groups := [...]struct {
+		name  string
+		scale *Unit
+	}{
+		{"TRL", &l.trillion},
+		{"BLN", &l.billion},
+		{"MLN", &l.million},
+		{"THS", &l.thousand},
+		{"", nil},
+	}
+	var words []string
+	for i, g := range groups {
+		s := 3*i + 1
+		words = append(words, b.hundreds(b.mid(s, 1)))
+		if l.and != "" {
+			cond := fmt.Sprintf("AND(-%s,-%s)", b.mid(s, 1), b.mid(s+1, 2))
+			if g.scale == nil {
+				// "one thousand and five"
+				cond = fmt.Sprintf("AND(-%s,OR(-%s,-%s))", b.mid(s+1, 2), b.mid(s, 1), b.mid(1, s-1))
+			}
+			words = append(words, fmt.Sprintf(`IF(%s,%s,"")`, cond, formulaString(encodeWord(l.and)+"z")))
 		}
-		// new index always after source index in slice
-		if uint32(i) == cs.Index() {
-			*doc = *copySrc
-			doc.FillIdAttr = &fillID
+		if g.scale == nil {
+			words = append(words, tens(b.mid(s+1, 1), b.mid(s+2, 1)))
 			break
 		}
+		words = append(words, b.tens(g.scale.Gender, b.mid(s+1, 1), b.mid(s+2, 1)))
+		words = append(words, fmt.Sprintf(`IF(-%s,%s,"")`, b.mid(s, 3),
+			b.scale(g.name, *g.scale, fmt.Sprintf("%s*AND(%s-1)", b.mid(s+2, 1), b.mid(s+1, 1)))))
 	}
-	cell.SetStyle(cs)
+	return strings.Join(words, "&")
 }
 
-// SetNumberFormat - set number format to cell by reference and save current cell style
-func SetNumberFormat(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, format string) {
-	// TODO: validate format
-	origStylePointer := cell.X().SAttr
-	cs := ss.AddCellStyle()
-	cs.SetNumberFormat(format)
-	nfID := cs.NumberFormat()
-	if origStylePointer == nil {
-		// don't need to copy style
-		cell.SetStyle(cs)
-		return
+// onesName - defined name of the words for numbers from 0 to 99 agreeing with gender
+func (b *spellBuilder) onesName(g Gender) string {
+	if g == Feminine {
+		return b.p + "N_1X" + b.s
 	}
-	var copySrc *sml.CT_Xf
-	for i, doc := range ss.X().CellXfs.Xf {
-		if uint32(i) == *origStylePointer {
-			copySrc = doc
+	return b.p + "N_0X" + b.s
+}
+
+// hundreds - formula of the word for hundreds digit d
+func (b *spellBuilder) hundreds(d string) string {
+	if b.portable {
+		return choose(d+"+1", wordItems(b.w.hundreds[:], "z"))
+	}
+	return fmt.Sprintf("INDEX(%sN_4%s,1,%s+1)", b.p, b.s, d)
+}
+
+// tens - formula of the words for tens digit t and units digit u agreeing with gender
+func (b *spellBuilder) tens(g Gender, t, u string) string {
+	if !b.portable {
+		return fmt.Sprintf("INDEX(%s,%s+1,%s+1)", b.onesName(g), t, u)
+	}
+	l := b.lang
+	ones := b.w.ones
+	if g == Feminine {
+		ones = b.w.onesFem
+	}
+	sep := "z"
+	if l.tensSep != "" {
+		sep = ""
+	}
+	text := choose(t+"+1", wordItems(b.w.tens[:], sep))
+	if l.tensSep != "" {
+		// "twenty-one", but "twenty"
+		text += fmt.Sprintf(`&IF(%s>1,IF(%s,%s,"z"),"")`, t, u, formulaString(encodeWord(l.tensSep)))
+	}
+	return fmt.Sprintf("IF(%s=1,%s,%s&%s)", t, choose(u+"+1", wordItems(b.w.teens[:], "z")), text,
+		choose(u+"+1", wordItems(ones[:], "z")))
+}
+
+// tensIf - same as tens, the words agree with gender g1 when cond is true and with g0
+// otherwise
+func (b *spellBuilder) tensIf(cond string, g1, g0 Gender, t, u string) string {
+	if g1 == g0 {
+		return b.tens(g0, t, u)
+	}
+	if !b.portable {
+		return fmt.Sprintf("INDEX(IF(%s,%s,%s),%s+1,%s+1)", cond, b.onesName(g1), b.onesName(g0), t, u)
+	}
+	return fmt.Sprintf("IF(%s,%s,%s)", cond, b.tens(g1, t, u), b.tens(g0, t, u))
+}
+
+// scale - formula of the scale word (thousand, million...) by key, the last digit of its
+// group or 0 for teens. name is the defined name of the Excel dialect
+func (b *spellBuilder) scale(name string, u Unit, key string) string {
+	if !b.portable {
+		return fmt.Sprintf("VLOOKUP(%s,%s%s%s,2)", key, b.p, name, b.s)
+	}
+	return choose(key+"+1", wordItems(b.lang.scaleForms(u, b.gc), "z"))
+}
+
+// decodeWords - formula putting back non-letter characters of the words in text
+func (l *Language) decodeWords(text string) string {
+	for _, ph := range wordPlaceholders {
+		if l.usesChar(ph.char) {
+			text = fmt.Sprintf(`SUBSTITUTE(%s,"%s","%s")`, text, ph.letter, ph.char)
 		}
-		// new index always after source index in slice
-		if uint32(i) == cs.Index() {
-			*doc = *copySrc
-			doc.NumFmtIdAttr = &nfID
-			break
+	}
+	return text
+}
+
+// unit - formula of unit name after a number in the case of the builder, see plural.
+// last3 is the expression of the last three digits of the number
+func (b *spellBuilder) unit(u Unit, abbr bool, last2, last3, whole string) string {
+	if abbr {
+		return formulaString(u.Abbr)
+	}
+	if b.gc == Nominative {
+		return b.plural(u, last2, whole)
+	}
+	d := b.lang.Declensions[u.Forms[0]]
+	// see caseForm
+	return fmt.Sprintf("IF(%s,IF(MOD(MAX(MOD(%s-11,100),9),10),%s,%s),%s)", last3, last2,
+		formulaString(d.Plural[b.gc-1]), formulaString(d.Singular[b.gc-1]), formulaString(d.Plural[0]))
+}
+
+// plural - formula choosing plural form of unit, last2 is the expression of the last
+// two digits of the number and whole is the expression of the number itself
+func (b *spellBuilder) plural(u Unit, last2, whole string) string {
+	switch b.lang.plural {
+	case pluralOne:
+		return fmt.Sprintf("IF(%s=1,%s,%s)", whole, formulaString(u.Forms[0]), formulaString(u.Forms[2]))
+	case pluralNone:
+		return formulaString(u.Forms[0])
+	}
+	key := fmt.Sprintf("MOD(MAX(MOD(%s-11,100),9),10)", last2)
+	if b.portable {
+		forms := make([]string, 10)
+		for d := range forms {
+			// key 0 is for numbers ending with 1, 1-3 for 2-4, others for the rest
+			forms[d] = formulaString(u.Forms[pluralForm(uint64(d+1))])
+		}
+		return choose(key+"+1", forms)
+	}
+	return fmt.Sprintf("VLOOKUP(%s,{0,%s;1,%s;4,%s},2)",
+		key, formulaString(u.Forms[0]), formulaString(u.Forms[1]), formulaString(u.Forms[2]))
+}
+
+// choose - CHOOSE formula of items by index
+func choose(index string, items []string) string {
+	return "CHOOSE(" + index + "," + strings.Join(items, ",") + ")"
+}
+
+// definedNames - vocabulary of the language as defined names used by its spell formula
+func (l *Language) definedNames(prefix string) map[string]string {
+	p := namePrefix(prefix, l)
+	names := map[string]string{
+		"N_4":  wordsArray(l.hundreds[:], ",", "z"),
+		"N_0":  `"` + strings.Repeat("0", spellDigits) + `"&MID(1/2,2,1)&"00"`,
+		"N_0X": l.tensMatrix(false, Nominative),
+		"N_1X": l.tensMatrix(true, Nominative),
+		"THS":  scaleLookup(l.scaleForms(l.thousand, Nominative)),
+		"MLN":  scaleLookup(l.scaleForms(l.million, Nominative)),
+		"BLN":  scaleLookup(l.scaleForms(l.billion, Nominative)),
+		"TRL":  scaleLookup(l.scaleForms(l.trillion, Nominative)),
+	}
+	prefixed := make(map[string]string, len(names))
+	for k, v := range names {
+		prefixed[p+k] = v
+	}
+	return prefixed
+}
+
+// usesChar - check if any word of the vocabulary contains c
+func (l *Language) usesChar(c string) bool {
+	words := []string{l.tensSep, l.and, l.minus}
+	for _, w := range [][10]string{l.ones, l.onesFem, l.teens, l.tens, l.hundreds} {
+		words = append(words, w[:]...)
+	}
+	for _, u := range []Unit{l.thousand, l.million, l.billion, l.trillion} {
+		words = append(words, u.Forms[:]...)
+	}
+	for _, w := range words {
+		if strings.Contains(w, c) {
+			return true
+		}
+	}
+	return c == " "
+}
+
+// encodeWord - replace non-letter characters of w with placeholders
+func encodeWord(w string) string {
+	for _, ph := range wordPlaceholders {
+		w = strings.Replace(w, ph.char, ph.letter, -1)
+	}
+	return w
+}
+
+// wordsArray - array constant of words, each followed by suffix
+func wordsArray(words []string, sep, suffix string) string {
+	return "{" + strings.Join(wordItems(words, suffix), sep) + "}"
+}
+
+// tensMatrix - array constant of words for numbers from 0 to 99 in case c, rows by tens
+// and columns by ones. It's a constant rather than a formula over arrays of tens and ones,
+// as the gooxml engine can't do operations on arrays of different shape
+func (l *Language) tensMatrix(feminine bool, c GrammaticalCase) string {
+	cw, _ := l.words(c)
+	rows := make([]string, 10)
+	for t := range rows {
+		words := make([]string, 10)
+		for u := range words {
+			words[u] = strings.Join(l.appendTriple(nil, uint64(10*t+u), feminine, cw), " ")
 		}
+		rows[t] = strings.Join(wordItems(words, "z"), ",")
 	}
-	cell.SetStyle(cs)
+	return "{" + strings.Join(rows, ";") + "}"
+}
+
+// wordItems - formula string literals of words, each followed by suffix
+func wordItems(words []string, suffix string) []string {
+	items := make([]string, len(words))
+	for i, w := range words {
+		if w == "" {
+			items[i] = `""`
+			continue
+		}
+		items[i] = formulaString(encodeWord(w) + suffix)
+	}
+	return items
+}
+
+// scaleLookup - VLOOKUP table of scale word forms by the last digit of the group
+// (0 for teens), see scaleForms
+func scaleLookup(forms []string) string {
+	var items []string
+	prev := ""
+	for d, form := range forms {
+		w := formulaString(encodeWord(form) + "z")
+		if w != prev {
+			items = append(items, fmt.Sprintf("%d,%s", d, w))
+			prev = w
+		}
+	}
+	return "{" + strings.Join(items, ";") + "}"
+}
+
+// formulaString - quote s as formula string literal
+func formulaString(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// SetSpellFormula - convert ref cell value(number) to words (Russian rubles), you need to run SetDefinedNamesRub()
+func SetSpellFormula(cell spreadsheet.Cell, ref string) error {
+	return SetSpellFormulaOptions(cell, ref, SpellOptions{})
+}
+
+// SetSpellFormulaOptions - convert ref cell value(number) to words in the language, currency
+// and style set by opts, the defined names must be set for the language and opts.Prefix.
+// For a long ref it gives ErrFormulaTooLong, use SetSpellFormulaIndirect then
+func SetSpellFormulaOptions(cell spreadsheet.Cell, ref string, opts SpellOptions) error {
+	f, err := GetSpellFormulaOptions(ref, opts)
+	if err != nil {
+		return err
+	}
+	cell.SetFormulaRaw(f)
+	return nil
+}
+
+// SetSpellValue - same as SetSpellFormula, but also stores the spelled text as the cached
+// formula result, so the cell isn't blank in viewers that don't recalculate formulas.
+// ref is evaluated in the sheet context and must give a number (an empty cell is 0)
+func SetSpellValue(sheet spreadsheet.Sheet, cell spreadsheet.Cell, ref string) error {
+	return SetSpellValueOptions(sheet, cell, ref, SpellOptions{})
+}
+
+// SetSpellValueOptions - same as SetSpellFormulaOptions, but also stores the text given by
+// SpellAmountOptions as the cached formula result, see SetSpellValue
+func SetSpellValueOptions(sheet spreadsheet.Sheet, cell spreadsheet.Cell, ref string, opts SpellOptions) error {
+	f, err := GetSpellFormulaOptions(ref, opts)
+	if err != nil {
+		return err
+	}
+	RegisterFunctions()
+	res := NewEvaluator().Eval(newFormulaContext(nil, sheet), ref).AsNumber()
+	if res.Type != formula.ResultTypeNumber {
+		return fmt.Errorf("can't spell %s: %q is not a number", ref, res.Value())
+	}
+	text := SpellAmountOptions(res.ValueNumber, opts)
+	if text == "" {
+		return fmt.Errorf("can't spell %s: %v is out of range", ref, res.ValueNumber)
+	}
+	cell.SetFormulaRaw(f)
+	cell.SetCachedFormulaResult(text)
+	return nil
+}
+
+// FillColor - fill cell by reference with color clr (RGBColor, IndexedColor or ThemeColor)
+// and save current cell style, returns the style index of the cell. Identical fills and
+// styles are shared by the cells
+func FillColor(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, clr Color) uint32 {
+	return ModifyCellStyle(ss, cell, func(d *StyleDelta) {
+		d.SetFillColor(clr)
+	})
+}
+
+// SetNumberFormat - set number format to cell by reference and save current cell style,
+// returns the style index of the cell. Identical number formats and styles are shared by
+// the cells. The format isn't checked, see SetNumberFormatChecked
+func SetNumberFormat(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, format string) uint32 {
+	return ModifyCellStyle(ss, cell, func(d *StyleDelta) {
+		d.SetNumberFormat(format)
+	})
 }
@@ -0,0 +1,115 @@
+package gooxmlhelpers
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// maxFormulaLen - the longest formula Excel opens, in characters
+const maxFormulaLen = 8192
+
+// ErrFormulaTooLong - the formula is longer than Excel allows, Excel refuses to open a file
+// with such formula. The helpers without a workbook can't fall back to a defined name, for
+// a long reference the callers must switch to GetSpellFormulaIndirect or
+// SetSpellFormulaIndirect
+var ErrFormulaTooLong = fmt.Errorf("formula is longer than %d characters", maxFormulaLen)
+
+// cellRef - cell reference in a formula, it's a whole reference when it isn't surrounded by
+// name characters
+var cellRef = regexp.MustCompile(`\$?[A-Za-z]{1,3}\$?[0-9]+`)
+
+// checkFormulaLen - check that Excel opens formula f
+func checkFormulaLen(f string) error {
+	if utf8.RuneCountInString(f) > maxFormulaLen {
+		return ErrFormulaTooLong
+	}
+	return nil
+}
+
+// GetSpellFormulaIndirect - same as GetSpellFormulaOptions, but when the formula is too
+// long because of a long ref ("'Расчет стоимости работ по договору'!$AB$1024") it defines
+// a name holding ref (opts.Prefix + "REF_" + hash of ref) and uses the name instead. Such
+// ref must have only absolute cell references with a sheet name, as a defined name refers
+// to the same cells from any cell
+func GetSpellFormulaIndirect(wb *spreadsheet.Workbook, ref string, opts SpellOptions) (string, error) {
+	f, err := GetSpellFormulaOptions(ref, opts)
+	if !errors.Is(err, ErrFormulaTooLong) {
+		return f, err
+	}
+	if !absoluteRef(ref) {
+		return "", fmt.Errorf("can't put reference %q in a defined name: cell references must be absolute and have a sheet name", ref)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(ref))
+	name := fmt.Sprintf("%sREF_%08X", opts.Prefix, h.Sum32())
+	// check the formula before the name is added
+	f, err = GetSpellFormulaOptions(name, opts)
+	if err != nil {
+		return "", err
+	}
+	if err := addDefinedNames(wb, map[string]string{name: ref}); err != nil {
+		return "", err
+	}
+	return f, nil
+}
+
+// SetSpellFormulaIndirect - convert ref cell value(number) to words like
+// SetSpellFormulaOptions, ref is put into a defined name when the formula is too long
+func SetSpellFormulaIndirect(wb *spreadsheet.Workbook, cell spreadsheet.Cell, ref string, opts SpellOptions) error {
+	f, err := GetSpellFormulaIndirect(wb, ref, opts)
+	if err != nil {
+		return err
+	}
+	cell.SetFormulaRaw(f)
+	return nil
+}
+
+// absoluteRef - check that all cell references of formula expression s are absolute and
+// have a sheet name, the second cell of a range takes the sheet name of the first one
+func absoluteRef(s string) bool {
+	// drop string literals and replace quoted sheet names with a plain one
+	var sb strings.Builder
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"':
+			quote = r
+		case r == '\'':
+			quote = r
+			sb.WriteRune('S')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	s = sb.String()
+	qualified := false
+	for _, m := range cellRef.FindAllStringIndex(s, -1) {
+		if m[0] > 0 && isNameChar(rune(s[m[0]-1])) ||
+			m[1] < len(s) && (isNameChar(rune(s[m[1]])) || strings.ContainsRune("(!", rune(s[m[1]]))) {
+			// a part of a name, a function or a sheet name
+			continue
+		}
+		ref := s[m[0]:m[1]]
+		if ref[0] != '$' || !strings.Contains(ref[1:], "$") {
+			return false
+		}
+		switch {
+		case m[0] > 0 && s[m[0]-1] == '!':
+			qualified = true
+		case m[0] > 0 && s[m[0]-1] == ':' && qualified:
+		default:
+			return false
+		}
+	}
+	return true
+}
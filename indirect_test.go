@@ -0,0 +1,43 @@
+package gooxmlhelpers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestSetSpellFormulaIndirect(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.SetName("Расчет стоимости работ")
+	if err := SetDefinedNamesRub(wb); err != nil {
+		t.Fatal(err)
+	}
+	// the sum of a column is too long to repeat in the formula
+	var terms []string
+	sum := 0.0
+	for i := 1; i <= 20; i++ {
+		sheet.Cell(fmt.Sprintf("A%d", i)).SetNumber(float64(i) * 100.5)
+		sum += float64(i) * 100.5
+		terms = append(terms, fmt.Sprintf("'Расчет стоимости работ'!$A$%d", i))
+	}
+	ref := strings.Join(terms, "+")
+	if _, err := GetSpellFormulaOptions(ref, SpellOptions{}); !errors.Is(err, ErrFormulaTooLong) {
+		t.Fatalf("GetSpellFormulaOptions: error %v, want ErrFormulaTooLong", err)
+	}
+	cell := sheet.Cell("B1")
+	if err := SetSpellFormulaIndirect(wb, cell, ref, SpellOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	RecalculateFormulas(wb)
+	if got, want := cell.GetString(), SpellRub(sum); got != want {
+		t.Errorf("indirect formula = %q, want %q", got, want)
+	}
+	// relative references can't be put in a defined name
+	if _, err := GetSpellFormulaIndirect(wb, strings.Replace(ref, "$A$1+", "A1+", 1), SpellOptions{}); err == nil {
+		t.Error("relative reference is put in a defined name")
+	}
+}
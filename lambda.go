@@ -0,0 +1,339 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"baliance.com/gooxml/spreadsheet"
+	"baliance.com/gooxml/spreadsheet/formula"
+	"baliance.com/gooxml/spreadsheet/reference"
+)
+
+// lambdaParam is the parameter of the spell lambdas as it's stored in the file
+const lambdaParam = "_xlpm.x"
+
+var (
+	// lambdaName - name of a lambda callable in the gooxml formula parser, which reads only
+	// upper case function names
+	lambdaName = regexp.MustCompile(`^[A-Z][A-Z0-9.]*$`)
+	// cellName - names looking like cell references, Excel doesn't allow them
+	cellName = regexp.MustCompile(`^[A-Z]{1,3}[0-9]+$`)
+)
+
+// SetSpellLambda - define name as Excel 365 LAMBDA spelling its argument in the style set
+// by opts, so the formula is stored once and the cells have a short call: =SPELLRUB(A1).
+// name must be upper case latin letters, digits and dots, the defined names of the
+// vocabulary must be set for DialectExcel as well. It's safe to call it again with the
+// same options
+func SetSpellLambda(wb *spreadsheet.Workbook, name string, opts SpellOptions) error {
+	if err := checkLambdaName(name); err != nil {
+		return err
+	}
+	if _, err := checkSpellRef(lambdaParam, opts.Prefix); err != nil {
+		return err
+	}
+	if err := opts.language().checkCase(opts.GramCase, opts.currency()); err != nil {
+		return err
+	}
+	body := strings.TrimPrefix(spellFormula(lambdaParam, opts), "=")
+	def := "_xlfn.LAMBDA(" + lambdaParam + "," + body + ")"
+	if err := checkFormula(def); err != nil {
+		return fmt.Errorf("can't build lambda %s: %s", name, err)
+	}
+	if err := checkFormulaLen(def); err != nil {
+		return fmt.Errorf("can't build lambda %s: %w", name, err)
+	}
+	return addDefinedNames(wb, map[string]string{name: def})
+}
+
+// GetSpellLambdaFormula - return formula calling lambda name set by SetSpellLambda for ref
+func GetSpellLambdaFormula(name, ref string) (string, error) {
+	if err := checkLambdaName(name); err != nil {
+		return "", err
+	}
+	ref, err := checkSpellRef(ref, "")
+	if err != nil {
+		return "", err
+	}
+	return "=" + name + "(" + ref + ")", nil
+}
+
+// SetSpellLambdaFormula - convert ref cell value(number) to words with lambda name set by
+// SetSpellLambda
+func SetSpellLambdaFormula(cell spreadsheet.Cell, name, ref string) error {
+	f, err := GetSpellLambdaFormula(name, ref)
+	if err != nil {
+		return err
+	}
+	cell.SetFormulaRaw(f)
+	return nil
+}
+
+// checkLambdaName - check that name can be a lambda name called in formulas
+func checkLambdaName(name string) error {
+	switch {
+	case !lambdaName.MatchString(name):
+		return fmt.Errorf("invalid lambda name %q: must be upper case latin letters, digits and dots", name)
+	case cellName.MatchString(name):
+		return fmt.Errorf("invalid lambda name %q: looks like a cell reference", name)
+	case isFunction(name) || functions[name] != nil || complexFunctions[name] != nil:
+		return fmt.Errorf("invalid lambda name %q: it's a function name", name)
+	}
+	return nil
+}
+
+// SetSpellFormulaRange - put num2spell formula (Russian rubles) into the cells of one
+// column targetRange ("C2:C10001") spelling the cells of sourceCol ("B") in the same rows,
+// you need to run SetDefinedNamesRub(). The formula is shared, so its text is stored once
+func SetSpellFormulaRange(sheet spreadsheet.Sheet, targetRange, sourceCol string) error {
+	return SetSpellFormulaRangeOptions(sheet, targetRange, sourceCol, SpellOptions{})
+}
+
+// SetSpellFormulaRangeOptions - same as SetSpellFormulaRange in the style set by opts
+func SetSpellFormulaRangeOptions(sheet spreadsheet.Sheet, targetRange, sourceCol string, opts SpellOptions) error {
+	from, to, err := reference.ParseRangeReference(targetRange)
+	if err != nil {
+		from, err = reference.ParseCellReference(targetRange)
+		if err != nil {
+			return fmt.Errorf("invalid target range %q: %s", targetRange, err)
+		}
+		to = from
+	}
+	if from.ColumnIdx != to.ColumnIdx || from.RowIdx > to.RowIdx {
+		return fmt.Errorf("invalid target range %q: must be one column from top to bottom", targetRange)
+	}
+	col := strings.ToUpper(strings.TrimPrefix(sourceCol, "$"))
+	if _, err := reference.ParseCellReference(col + "1"); err != nil || strings.ContainsAny(col, "0123456789$") {
+		return fmt.Errorf("invalid source column %q", sourceCol)
+	}
+	// the row is relative, so every cell of the range spells its own row
+	f, err := GetSpellFormulaOptions("$"+col+strconv.Itoa(int(from.RowIdx)), opts)
+	if err != nil {
+		return err
+	}
+	return sheet.Cell(from.String()).SetFormulaShared(f, to.RowIdx-from.RowIdx, 0)
+}
+
+// maxLambdaCalls - limit of the lambda calls inlined into one formula, so a lambda calling
+// itself gives an error
+const maxLambdaCalls = 100
+
+// evaluator - gooxml formula evaluator calling the LAMBDA defined names kept by
+// formulaContext, the gooxml formula engine knows nothing about LAMBDA
+type evaluator struct{}
+
+// NewEvaluator - return formula evaluator for FormulaContext, unlike formula.NewEvaluator()
+// it calls the LAMBDA defined names of the workbook: =SPELLRUB(A1)
+func NewEvaluator() formula.Evaluator {
+	return evaluator{}
+}
+
+// Eval - evaluate formula f in ctx, the lambda calls are replaced by the lambda bodies
+func (e evaluator) Eval(ctx formula.Context, f string) formula.Result {
+	if fc, ok := ctx.(formulaContext); ok && len(fc.lambdas) > 0 {
+		var res *formula.Result
+		if f, res = inlineLambdas(ctx, e, f, fc.lambdas); res != nil {
+			return *res
+		}
+	}
+	expr := formula.ParseString(f)
+	if expr == nil {
+		return formula.MakeErrorResult(fmt.Sprintf("unable to parse formula %s", f))
+	}
+	return expr.Eval(ctx, e)
+}
+
+// workbookLambdas - LAMBDA definitions of the workbook scope names of wb by upper case name
+func workbookLambdas(wb *spreadsheet.Workbook) map[string]string {
+	lambdas := map[string]string{}
+	for _, dn := range wb.DefinedNames() {
+		def := strings.TrimPrefix(dn.Content(), "=")
+		if dn.X().LocalSheetIdAttr != nil || !lambdaName.MatchString(strings.ToUpper(dn.Name())) ||
+			!strings.HasPrefix(def, "_xlfn.LAMBDA(") || !strings.HasSuffix(def, ")") {
+			continue
+		}
+		lambdas[strings.ToUpper(dn.Name())] = def
+	}
+	return lambdas
+}
+
+// inlineLambdas - replace the calls of lambdas in formula s by their bodies, the arguments
+// are evaluated by ev in ctx. For errors of the arguments and calls the result to return is
+// given instead
+func inlineLambdas(ctx formula.Context, ev formula.Evaluator, s string, lambdas map[string]string) (string, *formula.Result) {
+	calls := 0
+	var quote rune
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case isNameChar(r) && (i == 0 || !isNameChar(rune(s[i-1]))):
+			j := i
+			for j < len(s) && isNameChar(rune(s[j])) {
+				j++
+			}
+			name := strings.ToUpper(s[i:j])
+			def, ok := lambdas[name]
+			end := closingBracket(s, j)
+			if !ok || end < 0 {
+				i = j
+				continue
+			}
+			if calls++; calls > maxLambdaCalls {
+				res := formula.MakeErrorResult(fmt.Sprintf("more than %d lambda calls", maxLambdaCalls))
+				return "", &res
+			}
+			body, res := lambdaBody(ctx, ev, name, def, s[j+1:end])
+			if res != nil {
+				return "", res
+			}
+			// the body is scanned as well, it may call lambdas
+			s = s[:i] + "(" + body + ")" + s[end+1:]
+			continue
+		}
+		i++
+	}
+	return s, nil
+}
+
+// closingBracket - index of the bracket closing the one at open in s, -1 if there is no
+// bracket at open or it isn't closed
+func closingBracket(s string, open int) int {
+	if open >= len(s) || s[open] != '(' {
+		return -1
+	}
+	depth := 0
+	var quote rune
+	for i, r := range s[open:] {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth--; depth == 0 {
+				return open + i
+			}
+		}
+	}
+	return -1
+}
+
+// lambdaBody - body of lambda name with definition def, its parameters are replaced by the
+// values of args evaluated by ev in ctx
+func lambdaBody(ctx formula.Context, ev formula.Evaluator, name, def, args string) (string, *formula.Result) {
+	parts := splitArgs(def[len("_xlfn.LAMBDA(") : len(def)-1])
+	params, body := parts[:len(parts)-1], parts[len(parts)-1]
+	var values []string
+	if strings.TrimSpace(args) != "" {
+		values = splitArgs(args)
+	}
+	if len(params) != len(values) {
+		res := formula.MakeErrorResult(fmt.Sprintf("%s requires %d arguments", name, len(params)))
+		return "", &res
+	}
+	literals := map[string]string{}
+	for i, p := range params {
+		v, res := literal(ev.Eval(ctx, "="+values[i]))
+		if res != nil {
+			return "", res
+		}
+		literals[strings.TrimSpace(p)] = v
+	}
+	return replaceNames(body, literals), nil
+}
+
+// literal - formula literal of argument value r, or the result to return for errors and
+// values which can't be passed
+func literal(r formula.Result) (string, *formula.Result) {
+	switch r.Type {
+	case formula.ResultTypeNumber:
+		s := strconv.FormatFloat(r.ValueNumber, 'f', -1, 64)
+		if r.ValueNumber < 0 {
+			// the lexer reads only unsigned numbers
+			s = "(0" + s + ")"
+		}
+		return s, nil
+	case formula.ResultTypeString:
+		return formulaString(r.ValueString), nil
+	case formula.ResultTypeEmpty:
+		return "0", nil
+	case formula.ResultTypeError:
+		return "", &r
+	}
+	res := formula.MakeErrorResult("lambda argument must be a single value")
+	return "", &res
+}
+
+// splitArgs - split s by commas outside of brackets and literals
+func splitArgs(s string) []string {
+	var parts []string
+	depth, last := 0, 0
+	var quote rune
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '(' || r == '{':
+			depth++
+		case r == ')' || r == '}':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, s[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, s[last:])
+}
+
+// replaceNames - replace whole names of values in formula s outside of literals
+func replaceNames(s string, values map[string]string) string {
+	var sb strings.Builder
+	var quote rune
+	for i := 0; i < len(s); {
+		r := rune(s[i])
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case isNameChar(r) && (i == 0 || !isNameChar(rune(s[i-1]))):
+			j := i
+			for j < len(s) && isNameChar(rune(s[j])) {
+				j++
+			}
+			if v, ok := values[s[i:j]]; ok {
+				sb.WriteString(v)
+			} else {
+				sb.WriteString(s[i:j])
+			}
+			i = j
+			continue
+		}
+		sb.WriteByte(s[i])
+		i++
+	}
+	return sb.String()
+}
+
+// isNameChar - check if r can be a part of a name
+func isNameChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.'
+}
@@ -0,0 +1,109 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"baliance.com/gooxml/spreadsheet"
+	"baliance.com/gooxml/spreadsheet/formula"
+)
+
+func TestRecalculateFormulasLambda(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	if err := SetDefinedNamesRub(wb); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSpellLambda(wb, "SPELLRUB", SpellOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// other workbook with the same lambda name spelling other currency
+	other := spreadsheet.New()
+	otherSheet := other.AddSheet()
+	if err := SetDefinedNamesRub(other); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSpellLambda(other, "SPELLRUB", SpellOptions{Currency: USD}); err != nil {
+		t.Fatal(err)
+	}
+	for i, amount := range spellAmounts {
+		ref, cell := fmt.Sprintf("A%d", i+1), fmt.Sprintf("B%d", i+1)
+		for _, s := range []spreadsheet.Sheet{sheet, otherSheet} {
+			s.Cell(ref).SetNumber(amount)
+			if err := SetSpellLambdaFormula(s.Cell(cell), "SPELLRUB", ref); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	RecalculateFormulas(wb)
+	RecalculateFormulas(other)
+	for i, amount := range spellAmounts {
+		cell := fmt.Sprintf("B%d", i+1)
+		if got, want := sheet.Cell(cell).GetString(), SpellRub(amount); got != want {
+			t.Errorf("lambda of %v = %q, want %q", amount, got, want)
+		}
+		if got, want := otherSheet.Cell(cell).GetString(), SpellCurrency(amount, USD); got != want {
+			t.Errorf("lambda of other workbook of %v = %q, want %q", amount, got, want)
+		}
+	}
+}
+
+func TestCheckLambdaName(t *testing.T) {
+	for _, name := range []string{"SPELL", "SPELL.RUB", "SPELL2"} {
+		if err := checkLambdaName(name); err != nil {
+			t.Errorf("checkLambdaName(%q): %s", name, err)
+		}
+	}
+	for _, name := range []string{"", "spell", "A1", "SUM", "MID", "YEAR", "SPELL RUB"} {
+		if err := checkLambdaName(name); err == nil {
+			t.Errorf("checkLambdaName(%q) gives no error", name)
+		}
+	}
+}
+
+func TestLambdaErrors(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	if err := SetDefinedNamesRub(wb); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetSpellLambda(wb, "SPELLRUB", SpellOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	wb.AddDefinedName("LOOP", "_xlfn.LAMBDA(_xlpm.x,LOOP(_xlpm.x+1))")
+	wb.AddDefinedName("PING", "_xlfn.LAMBDA(_xlpm.x,PONG(_xlpm.x))")
+	wb.AddDefinedName("PONG", "_xlfn.LAMBDA(_xlpm.x,PING(_xlpm.x))")
+	wb.AddDefinedName("TWICE", "_xlfn.LAMBDA(_xlpm.x,SPELLRUB(_xlpm.x)&\" \"&SPELLRUB(_xlpm.x*2))")
+	sheet.Cell("A1").SetNumber(21)
+	sheet.Cell("A2").SetNumber(5)
+	tests := []struct {
+		formula string
+		want    string
+		err     string
+	}{
+		{"SPELLRUB(A1)", SpellRub(21), ""},
+		{"spellrub(A2)&\"!\"", SpellRub(5) + "!", ""},
+		{"TWICE(A1)", SpellRub(21) + " " + SpellRub(42), ""},
+		{"\"SPELLRUB(A1)\"", "SPELLRUB(A1)", ""},
+		{"SPELLRUB(A1,A2)", "", "SPELLRUB requires 1 arguments"},
+		{"SPELLRUB()", "", "SPELLRUB requires 1 arguments"},
+		{"LOOP(1)", "", fmt.Sprintf("more than %d lambda calls", maxLambdaCalls)},
+		{"PING(1)", "", fmt.Sprintf("more than %d lambda calls", maxLambdaCalls)},
+		{"SPELLRUB(A1:A2)", "", "lambda argument must be a single value"},
+	}
+	ev := NewEvaluator()
+	for _, tt := range tests {
+		res := ev.Eval(FormulaContext(wb, sheet), tt.formula)
+		switch {
+		case tt.err != "":
+			if res.Type != formula.ResultTypeError || !strings.Contains(res.ErrorMessage, tt.err) {
+				t.Errorf("%s = %v %q, want error %q", tt.formula, res.Type, res.Value(), tt.err)
+			}
+		case res.Type == formula.ResultTypeError:
+			t.Errorf("%s: %s", tt.formula, res.ErrorMessage)
+		case res.Value() != tt.want:
+			t.Errorf("%s = %q, want %q", tt.formula, res.Value(), tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package gooxmlhelpers
+
+// pluralRule - how a language chooses plural form of a unit after a number
+type pluralRule int
+
+const (
+	// one/few/many by the last two digits: рубль, рубля, рублей
+	pluralSlavic pluralRule = iota
+	// singular only for exactly one: dollar, dollars
+	pluralOne
+	// unit name doesn't change after numbers: теңге
+	pluralNone
+)
+
+// Language - vocabulary and grammar rules used to spell numbers
+type Language struct {
+	// Code - ISO 639-1 language code
+	Code string
+	// Currencies - built-in currencies with unit names in this language
+	Currencies map[string]Currency
+	// Declensions - unit names in the oblique cases by the nominative singular name, they
+	// are needed to spell amounts in those cases
+	Declensions map[string]Declension
+
+	// prefix of the defined names, Russian keeps the original names
+	prefix   string
+	zero     string
+	ones     [10]string
+	onesFem  [10]string
+	teens    [10]string
+	tens     [10]string
+	hundreds [10]string
+	thousand Unit
+	million  Unit
+	billion  Unit
+	trillion Unit
+	// minus is put before negative numbers
+	minus  string
+	plural pluralRule
+	// tensSep joins tens and ones into one word: "twenty-one"
+	tensSep string
+	// and is put between hundreds and the rest of the group: "one hundred and five"
+	and string
+	// whole and fractions (tenths, hundredths, thousandths) spell decimal quantities:
+	// "две целых пять десятых литра", the unit takes form fracForm after a fraction
+	whole     Unit
+	fractions [3]Unit
+	fracForm  int
+	// fracFirst puts the fraction name before the numerator: "оннан бес"
+	fracFirst bool
+	// cases - words of numbers in the oblique cases
+	cases map[GrammaticalCase]caseWords
+}
+
+// Built-in languages
+var (
+	Russian = &Language{
+		Code:        "ru",
+		Currencies:  Currencies,
+		Declensions: russianDeclensions,
+		zero:        "ноль",
+		ones:        [10]string{"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"},
+		onesFem:     [10]string{"", "одна", "две", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"},
+		teens:       [10]string{"десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"},
+		tens:        [10]string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"},
+		hundreds:    [10]string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"},
+		thousand:    Unit{Feminine, [3]string{"тысяча", "тысячи", "тысяч"}, "тыс."},
+		million:     Unit{Masculine, [3]string{"миллион", "миллиона", "миллионов"}, "млн"},
+		billion:     Unit{Masculine, [3]string{"миллиард", "миллиарда", "миллиардов"}, "млрд"},
+		trillion:    Unit{Masculine, [3]string{"триллион", "триллиона", "триллионов"}, "трлн"},
+		minus:       "минус",
+		plural:      pluralSlavic,
+		whole:       Unit{Feminine, [3]string{"целая", "целых", "целых"}, "цел."},
+		fractions: [3]Unit{
+			{Feminine, [3]string{"десятая", "десятых", "десятых"}, ""},
+			{Feminine, [3]string{"сотая", "сотых", "сотых"}, ""},
+			{Feminine, [3]string{"тысячная", "тысячных", "тысячных"}, ""},
+		},
+		fracForm: 1,
+		cases:    russianCases,
+	}
+	Ukrainian = &Language{
+		Code:   "uk",
+		prefix: "UK_",
+		Currencies: map[string]Currency{
+			"UAH": {"UAH", Unit{Feminine, [3]string{"гривня", "гривні", "гривень"}, "грн."}, Unit{Feminine, [3]string{"копійка", "копійки", "копійок"}, "коп."}},
+			"USD": {"USD", Unit{Masculine, [3]string{"долар", "долари", "доларів"}, "дол."}, Unit{Masculine, [3]string{"цент", "центи", "центів"}, "цент."}},
+			"EUR": {"EUR", Unit{Masculine, [3]string{"євро", "євро", "євро"}, "євро"}, Unit{Masculine, [3]string{"цент", "центи", "центів"}, "цент."}},
+		},
+		zero:     "нуль",
+		ones:     [10]string{"", "один", "два", "три", "чотири", "п'ять", "шість", "сім", "вісім", "дев'ять"},
+		onesFem:  [10]string{"", "одна", "дві", "три", "чотири", "п'ять", "шість", "сім", "вісім", "дев'ять"},
+		teens:    [10]string{"десять", "одинадцять", "дванадцять", "тринадцять", "чотирнадцять", "п'ятнадцять", "шістнадцять", "сімнадцять", "вісімнадцять", "дев'ятнадцять"},
+		tens:     [10]string{"", "", "двадцять", "тридцять", "сорок", "п'ятдесят", "шістдесят", "сімдесят", "вісімдесят", "дев'яносто"},
+		hundreds: [10]string{"", "сто", "двісті", "триста", "чотириста", "п'ятсот", "шістсот", "сімсот", "вісімсот", "дев'ятсот"},
+		thousand: Unit{Feminine, [3]string{"тисяча", "тисячі", "тисяч"}, "тис."},
+		million:  Unit{Masculine, [3]string{"мільйон", "мільйони", "мільйонів"}, "млн"},
+		billion:  Unit{Masculine, [3]string{"мільярд", "мільярди", "мільярдів"}, "млрд"},
+		trillion: Unit{Masculine, [3]string{"трильйон", "трильйони", "трильйонів"}, "трлн"},
+		minus:    "мінус",
+		plural:   pluralSlavic,
+		whole:    Unit{Feminine, [3]string{"ціла", "цілих", "цілих"}, "ціл."},
+		fractions: [3]Unit{
+			{Feminine, [3]string{"десята", "десятих", "десятих"}, ""},
+			{Feminine, [3]string{"сота", "сотих", "сотих"}, ""},
+			{Feminine, [3]string{"тисячна", "тисячних", "тисячних"}, ""},
+		},
+		fracForm: 1,
+	}
+	Belarusian = &Language{
+		Code:   "be",
+		prefix: "BE_",
+		Currencies: map[string]Currency{
+			"BYN": {"BYN", Unit{Masculine, [3]string{"рубель", "рублі", "рублёў"}, "руб."}, Unit{Feminine, [3]string{"капейка", "капейкі", "капеек"}, "кап."}},
+			"USD": {"USD", Unit{Masculine, [3]string{"долар", "долары", "долараў"}, "дол."}, Unit{Masculine, [3]string{"цэнт", "цэнты", "цэнтаў"}, "цэнт."}},
+			"EUR": {"EUR", Unit{Masculine, [3]string{"еўра", "еўра", "еўра"}, "еўра"}, Unit{Masculine, [3]string{"цэнт", "цэнты", "цэнтаў"}, "цэнт."}},
+		},
+		zero:     "нуль",
+		ones:     [10]string{"", "адзін", "два", "тры", "чатыры", "пяць", "шэсць", "сем", "восем", "дзевяць"},
+		onesFem:  [10]string{"", "адна", "дзве", "тры", "чатыры", "пяць", "шэсць", "сем", "восем", "дзевяць"},
+		teens:    [10]string{"дзесяць", "адзінаццаць", "дванаццаць", "трынаццаць", "чатырнаццаць", "пятнаццаць", "шаснаццаць", "сямнаццаць", "васямнаццаць", "дзевятнаццаць"},
+		tens:     [10]string{"", "", "дваццаць", "трыццаць", "сорак", "пяцьдзясят", "шэсцьдзясят", "семдзесят", "восемдзесят", "дзевяноста"},
+		hundreds: [10]string{"", "сто", "дзвесце", "трыста", "чатырыста", "пяцьсот", "шэсцьсот", "семсот", "восемсот", "дзевяцьсот"},
+		thousand: Unit{Feminine, [3]string{"тысяча", "тысячы", "тысяч"}, "тыс."},
+		million:  Unit{Masculine, [3]string{"мільён", "мільёны", "мільёнаў"}, "млн"},
+		billion:  Unit{Masculine, [3]string{"мільярд", "мільярды", "мільярдаў"}, "млрд"},
+		trillion: Unit{Masculine, [3]string{"трыльён", "трыльёны", "трыльёнаў"}, "трлн"},
+		minus:    "мінус",
+		plural:   pluralSlavic,
+		whole:    Unit{Feminine, [3]string{"цэлая", "цэлых", "цэлых"}, "цэл."},
+		fractions: [3]Unit{
+			{Feminine, [3]string{"дзясятая", "дзясятых", "дзясятых"}, ""},
+			{Feminine, [3]string{"сотая", "сотых", "сотых"}, ""},
+			{Feminine, [3]string{"тысячная", "тысячных", "тысячных"}, ""},
+		},
+		fracForm: 1,
+	}
+	Kazakh = &Language{
+		Code:   "kk",
+		prefix: "KK_",
+		Currencies: map[string]Currency{
+			"KZT": {"KZT", Unit{Masculine, [3]string{"теңге", "теңге", "теңге"}, "тг"}, Unit{Masculine, [3]string{"тиын", "тиын", "тиын"}, "тиын"}},
+			"USD": {"USD", Unit{Masculine, [3]string{"доллар", "доллар", "доллар"}, "доллар"}, Unit{Masculine, [3]string{"цент", "цент", "цент"}, "цент"}},
+			"EUR": {"EUR", Unit{Masculine, [3]string{"еуро", "еуро", "еуро"}, "еуро"}, Unit{Masculine, [3]string{"цент", "цент", "цент"}, "цент"}},
+		},
+		zero:     "нөл",
+		ones:     [10]string{"", "бір", "екі", "үш", "төрт", "бес", "алты", "жеті", "сегіз", "тоғыз"},
+		onesFem:  [10]string{"", "бір", "екі", "үш", "төрт", "бес", "алты", "жеті", "сегіз", "тоғыз"},
+		teens:    [10]string{"он", "он бір", "он екі", "он үш", "он төрт", "он бес", "он алты", "он жеті", "он сегіз", "он тоғыз"},
+		tens:     [10]string{"", "", "жиырма", "отыз", "қырық", "елу", "алпыс", "жетпіс", "сексен", "тоқсан"},
+		hundreds: [10]string{"", "жүз", "екі жүз", "үш жүз", "төрт жүз", "бес жүз", "алты жүз", "жеті жүз", "сегіз жүз", "тоғыз жүз"},
+		thousand: Unit{Masculine, [3]string{"мың", "мың", "мың"}, "мың"},
+		million:  Unit{Masculine, [3]string{"миллион", "миллион", "миллион"}, "млн"},
+		billion:  Unit{Masculine, [3]string{"миллиард", "миллиард", "миллиард"}, "млрд"},
+		trillion: Unit{Masculine, [3]string{"триллион", "триллион", "триллион"}, "трлн"},
+		minus:    "минус",
+		plural:   pluralNone,
+		whole:    Unit{Masculine, [3]string{"бүтін", "бүтін", "бүтін"}, "бүт."},
+		fractions: [3]Unit{
+			{Masculine, [3]string{"оннан", "оннан", "оннан"}, ""},
+			{Masculine, [3]string{"жүзден", "жүзден", "жүзден"}, ""},
+			{Masculine, [3]string{"мыңнан", "мыңнан", "мыңнан"}, ""},
+		},
+		fracForm:  0,
+		fracFirst: true,
+	}
+	English = &Language{
+		Code:   "en",
+		prefix: "EN_",
+		Currencies: map[string]Currency{
+			"USD": {"USD", Unit{Masculine, [3]string{"dollar", "dollars", "dollars"}, "USD"}, Unit{Masculine, [3]string{"cent", "cents", "cents"}, "ct."}},
+			"EUR": {"EUR", Unit{Masculine, [3]string{"euro", "euros", "euros"}, "EUR"}, Unit{Masculine, [3]string{"cent", "cents", "cents"}, "ct."}},
+			"GBP": {"GBP", Unit{Masculine, [3]string{"pound", "pounds", "pounds"}, "GBP"}, Unit{Masculine, [3]string{"penny", "pence", "pence"}, "p."}},
+			"RUB": {"RUB", Unit{Masculine, [3]string{"ruble", "rubles", "rubles"}, "RUB"}, Unit{Masculine, [3]string{"kopeck", "kopecks", "kopecks"}, "kop."}},
+			"KZT": {"KZT", Unit{Masculine, [3]string{"tenge", "tenge", "tenge"}, "KZT"}, Unit{Masculine, [3]string{"tiyn", "tiyn", "tiyn"}, "tiyn"}},
+			"BYN": {"BYN", Unit{Masculine, [3]string{"Belarusian ruble", "Belarusian rubles", "Belarusian rubles"}, "BYN"}, Unit{Masculine, [3]string{"kopeck", "kopecks", "kopecks"}, "kop."}},
+			"UAH": {"UAH", Unit{Masculine, [3]string{"hryvnia", "hryvnias", "hryvnias"}, "UAH"}, Unit{Masculine, [3]string{"kopiyka", "kopiykas", "kopiykas"}, "kop."}},
+			"CNY": {"CNY", Unit{Masculine, [3]string{"yuan", "yuan", "yuan"}, "CNY"}, Unit{Masculine, [3]string{"fen", "fen", "fen"}, "fen"}},
+		},
+		zero:     "zero",
+		ones:     [10]string{"", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+		onesFem:  [10]string{"", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine"},
+		teens:    [10]string{"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"},
+		tens:     [10]string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"},
+		hundreds: [10]string{"", "one hundred", "two hundred", "three hundred", "four hundred", "five hundred", "six hundred", "seven hundred", "eight hundred", "nine hundred"},
+		thousand: Unit{Masculine, [3]string{"thousand", "thousand", "thousand"}, "th."},
+		million:  Unit{Masculine, [3]string{"million", "million", "million"}, "mln"},
+		billion:  Unit{Masculine, [3]string{"billion", "billion", "billion"}, "bn"},
+		trillion: Unit{Masculine, [3]string{"trillion", "trillion", "trillion"}, "tn"},
+		minus:    "minus",
+		plural:   pluralOne,
+		tensSep:  "-",
+		and:      "and",
+		whole:    Unit{Masculine, [3]string{"and", "and", "and"}, "and"},
+		fractions: [3]Unit{
+			{Masculine, [3]string{"tenth", "tenths", "tenths"}, ""},
+			{Masculine, [3]string{"hundredth", "hundredths", "hundredths"}, ""},
+			{Masculine, [3]string{"thousandth", "thousandths", "thousandths"}, ""},
+		},
+		fracForm: 2,
+	}
+)
+
+// Languages - built-in languages by code
+var Languages = map[string]*Language{
+	Russian.Code:    Russian,
+	Ukrainian.Code:  Ukrainian,
+	Belarusian.Code: Belarusian,
+	Kazakh.Code:     Kazakh,
+	English.Code:    English,
+}
+
+// pluralForm - index in Unit.Forms to use after n
+func (l *Language) pluralForm(n uint64) int {
+	switch l.plural {
+	case pluralOne:
+		if n == 1 {
+			return 0
+		}
+		return 2
+	case pluralNone:
+		return 0
+	}
+	return pluralForm(n)
+}
@@ -0,0 +1,145 @@
+package gooxmlhelpers
+
+import (
+	"strings"
+	"testing"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// baselineNames - defined names set by the first version of SetDefinedNamesRub
+var baselineNames = map[string]string{
+	"n_1": `{"","одинz","дваz","триz","четыреz","пятьz","шестьz","семьz","восемьz","девятьz"}`,
+	"n_2": `{"десятьz","одиннадцатьz","двенадцатьz","тринадцатьz","четырнадцатьz","пятнадцатьz","шестнадцатьz","семнадцатьz","восемнадцатьz","девятнадцатьz"}`,
+	"n_3": `{"";1;"двадцатьz";"тридцатьz";"сорокz";"пятьдесятz";"шестьдесятz";"семьдесятz";"восемьдесятz";"девяностоz"}`,
+	"n_4": `{"","стоz","двестиz","тристаz","четырестаz","пятьсотz","шестьсотz","семьсотz","восемьсотz","девятьсотz"}`,
+	"n_5": `{"","однаz","двеz","триz","четыреz","пятьz","шестьz","семьz","восемьz","девятьz"}`,
+	"n0":  `"000000000000"&MID(1/2,2,1)&"00"`,
+	"n0x": `IF(n_3=1,n_2,n_3&n_1)`,
+	"n1x": `IF(n_3=1,n_2,n_3&n_5)`,
+	"mil": `{0,"овz";1,"z";2,"аz";5,"овz"}`,
+	"ths": `{0,"тысячz";1,"тысячаz";2,"тысячиz";5,"тысячz"}`,
+}
+
+// workbookNames - workbook scope names of wb with their content
+func workbookNames(wb *spreadsheet.Workbook) map[string]string {
+	names := map[string]string{}
+	for _, dn := range wb.DefinedNames() {
+		if dn.X().LocalSheetIdAttr == nil {
+			names[dn.Name()] = dn.Content()
+		}
+	}
+	return names
+}
+
+func TestSetDefinedNamesLegacy(t *testing.T) {
+	tests := []struct {
+		name   string
+		legacy map[string]string
+	}{
+		{"baseline", baselineNames},
+		{"upper case", func() map[string]string {
+			names := map[string]string{}
+			for k, v := range baselineNames {
+				names[strings.ToUpper(k)] = strings.ToUpper(v)
+			}
+			return names
+		}()},
+	}
+	for _, tt := range tests {
+		wb := spreadsheet.New()
+		for k, v := range tt.legacy {
+			wb.AddDefinedName(k, v)
+		}
+		// a sheet scope name isn't a part of the vocabulary
+		wb.AddDefinedName("N_1", "Sheet1!$A$1").X().LocalSheetIdAttr = new(uint32)
+		for i := 0; i < 2; i++ {
+			if err := SetDefinedNamesRub(wb); err != nil {
+				t.Fatalf("%s: SetDefinedNamesRub: %s", tt.name, err)
+			}
+		}
+		got := workbookNames(wb)
+		for k, v := range Russian.definedNames("") {
+			if !strings.EqualFold(got[k], v) && !strings.EqualFold(got[strings.ToLower(k)], v) {
+				t.Errorf("%s: name %s = %q, want %q", tt.name, k, got[k], v)
+			}
+		}
+		for _, k := range legacyNames {
+			if _, ok := got[k]; ok {
+				t.Errorf("%s: legacy name %s isn't removed", tt.name, k)
+			}
+			if _, ok := got[strings.ToLower(k)]; ok {
+				t.Errorf("%s: legacy name %s isn't removed", tt.name, strings.ToLower(k))
+			}
+		}
+		if len(wb.DefinedNames()) != len(Russian.definedNames(""))+1 {
+			t.Errorf("%s: %d names, want %d", tt.name, len(wb.DefinedNames()), len(Russian.definedNames(""))+1)
+		}
+	}
+}
+
+func TestSetDefinedNamesLegacyKept(t *testing.T) {
+	// N_1 and MIL without the legacy N0 are names of the user
+	wb := spreadsheet.New()
+	wb.AddDefinedName("N_1", "Sheet1!$A$1")
+	wb.AddDefinedName("MIL", "1000000")
+	if err := SetDefinedNamesRub(wb); err != nil {
+		t.Fatal(err)
+	}
+	got := workbookNames(wb)
+	if got["N_1"] != "Sheet1!$A$1" || got["MIL"] != "1000000" {
+		t.Errorf("names of the user are changed: %v", got)
+	}
+}
+
+func TestSetDefinedNamesLegacyUsed(t *testing.T) {
+	tests := []struct {
+		name string
+		set  func(wb *spreadsheet.Workbook)
+		kept bool
+	}{
+		{"cell formula", func(wb *spreadsheet.Workbook) {
+			wb.Sheets()[0].Cell("B1").SetFormulaRaw(`TRIM(INDEX(n0x,1,2)&" руб.")`)
+		}, true},
+		{"other name", func(wb *spreadsheet.Workbook) {
+			wb.AddDefinedName("MYSPELL", `PROPER(INDEX(N1X,1,2))`)
+		}, true},
+		{"literal", func(wb *spreadsheet.Workbook) {
+			wb.Sheets()[0].Cell("B1").SetFormulaRaw(`"N0X"&'N1X'!A1`)
+		}, false},
+		{"new names", func(wb *spreadsheet.Workbook) {
+			wb.Sheets()[0].Cell("B1").SetFormulaRaw(GetSpellFormula("A1"))
+		}, false},
+	}
+	for _, tt := range tests {
+		wb := spreadsheet.New()
+		wb.AddSheet()
+		for k, v := range baselineNames {
+			wb.AddDefinedName(k, v)
+		}
+		tt.set(wb)
+		if err := SetDefinedNamesRub(wb); err != nil {
+			t.Fatalf("%s: SetDefinedNamesRub: %s", tt.name, err)
+		}
+		got := map[string]string{}
+		for k, v := range workbookNames(wb) {
+			got[strings.ToUpper(k)] = v
+		}
+		for _, k := range legacyNames {
+			v, ok := baselineNames[strings.ToLower(k)]
+			if !ok {
+				continue
+			}
+			if _, ok := got[k]; ok != tt.kept {
+				t.Errorf("%s: legacy name %s kept %t, want %t", tt.name, k, ok, tt.kept)
+			} else if ok && got[k] != v {
+				t.Errorf("%s: legacy name %s = %q, want %q", tt.name, k, got[k], v)
+			}
+		}
+		for k := range Russian.definedNames("") {
+			if _, ok := got[k]; !ok {
+				t.Errorf("%s: name %s isn't added", tt.name, k)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	"baliance.com/gooxml/spreadsheet"
+	"baliance.com/gooxml/spreadsheet/format"
+)
+
+// maxNumberFormatLen - the longest number format code Excel accepts, in characters
+const maxNumberFormatLen = 255
+
+// maxNumberFormatSections - sections of a number format: positive, negative, zero and text
+const maxNumberFormatSections = 4
+
+// bracketCode - valid contents of brackets in a number format: color, condition, currency
+// and locale, elapsed time, numeral system
+var bracketCode = regexp.MustCompile(`^(?i:black|blue|cyan|green|magenta|red|white|yellow|color([1-9]|[1-4][0-9]|5[0-6])|` +
+	`(<|<=|<>|=|>|>=)-?[0-9]+(\.[0-9]+)?(e[+-]?[0-9]+)?|\$[^\]]*|h+|m+|s+|dbnum[1-4]|natnum[0-9]+)$`)
+
+// NumberFormatError - invalid number format code, Pos is the number of the character
+// where the error is found, starting with 1
+type NumberFormatError struct {
+	Code string
+	Pos  int
+	Msg  string
+}
+
+// Error - description of the error with its position
+func (e *NumberFormatError) Error() string {
+	return fmt.Sprintf("invalid number format %q at character %d: %s", e.Code, e.Pos, e.Msg)
+}
+
+// ValidateNumberFormat - check that Excel accepts number format code: quotes and brackets
+// are closed, bracket codes are known, escapes and fill characters have a character after
+// them, there are at most four sections and exponents have digits. Returns
+// *NumberFormatError
+func ValidateNumberFormat(code string) error {
+	fail := func(pos int, msg string, args ...interface{}) error {
+		return &NumberFormatError{Code: code, Pos: pos, Msg: fmt.Sprintf(msg, args...)}
+	}
+	if code == "" {
+		return fail(1, "empty format")
+	}
+	if n := utf8.RuneCountInString(code); n > maxNumberFormatLen {
+		return fail(maxNumberFormatLen+1, "format is longer than %d characters", maxNumberFormatLen)
+	}
+	rs := []rune(code)
+	sections, sectionStart, exp := 1, 0, -1
+	for i := 0; i < len(rs); i++ {
+		switch rs[i] {
+		case '"':
+			j := i + 1
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			if j == len(rs) {
+				return fail(i+1, "unterminated quoted text")
+			}
+			i = j
+		case '[':
+			j := i + 1
+			for j < len(rs) && rs[j] != ']' {
+				j++
+			}
+			if j == len(rs) {
+				return fail(i+1, "unterminated bracket")
+			}
+			if b := string(rs[i+1 : j]); !bracketCode.MatchString(b) {
+				return fail(i+1, "unknown code [%s]", b)
+			}
+			i = j
+		case '\\', '_', '*':
+			if i+1 == len(rs) {
+				return fail(i+1, "no character after %c", rs[i])
+			}
+			i++
+		case 'E', 'e':
+			if exp < 0 && i+1 < len(rs) && (rs[i+1] == '+' || rs[i+1] == '-') {
+				// the format lexer of gooxml knows only the upper case exponent
+				exp, rs[i] = i, 'E'
+			}
+		case ';':
+			if err := checkSection(rs[sectionStart:i], exp, fail); err != nil {
+				return err
+			}
+			if sections++; sections > maxNumberFormatSections {
+				return fail(i+1, "more than %d sections", maxNumberFormatSections)
+			}
+			sectionStart, exp = i+1, -1
+		}
+	}
+	return checkSection(rs[sectionStart:], exp, fail)
+}
+
+// checkSection - check a section of a number format with the format lexer of gooxml, exp
+// is the position of the exponent in the format or -1
+func checkSection(section []rune, exp int, fail func(int, string, ...interface{}) error) error {
+	for _, f := range format.Parse(string(section)) {
+		if !f.IsExponential {
+			continue
+		}
+		digits := false
+		for _, t := range f.Exponent {
+			if t.Type == format.FmtTypeDigit || t.Type == format.FmtTypeDigitOpt {
+				digits = true
+			}
+		}
+		if !digits && exp >= 0 {
+			return fail(exp+1, "no digits in exponent")
+		}
+	}
+	return nil
+}
+
+// SetNumberFormatChecked - same as SetNumberFormat, but the format is checked by
+// ValidateNumberFormat first and an invalid one isn't set. An error is returned as well
+// when the stylesheet or the style of the cell is broken, see ModifyCellStyleChecked
+func SetNumberFormatChecked(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, format string) (uint32, error) {
+	if err := ValidateNumberFormat(format); err != nil {
+		return 0, err
+	}
+	return ModifyCellStyleChecked(ss, cell, func(d *StyleDelta) {
+		d.SetNumberFormat(format)
+	})
+}
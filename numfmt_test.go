@@ -0,0 +1,197 @@
+package gooxmlhelpers
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"baliance.com/gooxml"
+	"baliance.com/gooxml/color"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestValidateNumberFormat(t *testing.T) {
+	valid := []string{
+		"General", "0", "0.00", "# ##0.00", "#,##0.00;[Red]-#,##0.00", "0%",
+		"0.00E+00", "0.00e+00", "##0.0E-0", "0.0e-00", `0.00 "руб."`, `0.00\е`,
+		"dd.mm.yyyy", "[$-419]d mmmm yyyy", "_-* #,##0.00_-", "0;-0;0;@",
+	}
+	for _, code := range valid {
+		if err := ValidateNumberFormat(code); err != nil {
+			t.Errorf("ValidateNumberFormat(%q): %s", code, err)
+		}
+	}
+	invalid := []struct {
+		code string
+		pos  int
+	}{
+		{"", 1},
+		{"0.00E+", 5},
+		{"0.00e+", 5},
+		{"0.00e-;0", 5},
+		{"0;0e+", 4},
+		{`0.00 "руб.`, 6},
+		{"[Redd]0", 1},
+		{"0.00\\", 5},
+		{"0;0;0;0;0", 8},
+	}
+	for _, tt := range invalid {
+		err := ValidateNumberFormat(tt.code)
+		nfe, ok := err.(*NumberFormatError)
+		if !ok {
+			t.Errorf("ValidateNumberFormat(%q) = %v, want *NumberFormatError", tt.code, err)
+			continue
+		}
+		if nfe.Pos != tt.pos || nfe.Code != tt.code {
+			t.Errorf("ValidateNumberFormat(%q) = %s, want position %d", tt.code, err, tt.pos)
+		}
+	}
+}
+
+// numFmtOf - number format id of the style of cell
+func numFmtOf(ss spreadsheet.StyleSheet, cell spreadsheet.Cell) uint32 {
+	xf := ss.X().CellXfs.Xf[*cell.X().SAttr]
+	if xf.NumFmtIdAttr == nil {
+		return 0
+	}
+	return *xf.NumFmtIdAttr
+}
+
+func TestSetNumberFormatIDs(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	ss := wb.StyleSheet
+	for code, id := range map[string]uint32{"General": 0, "0": 1, "0.00": 2, "#,##0": 3, "#,##0.00": 4, "0%": 9, "0.00E+00": 11, "@": 49} {
+		cell := sheet.Cell("A1")
+		SetNumberFormat(ss, cell, code)
+		if got := numFmtOf(ss, cell); got != id {
+			t.Errorf("format %q got id %d, want built-in %d", code, got, id)
+		}
+	}
+	if ss.X().NumFmts != nil && len(ss.X().NumFmts.NumFmt) != 0 {
+		t.Errorf("built-in formats added %d custom ones", len(ss.X().NumFmts.NumFmt))
+	}
+
+	// a custom format of the file is reused, a new one gets an id after the ones of gooxml
+	nfs := sml.NewCT_NumFmts()
+	nfs.NumFmt = []*sml.CT_NumFmt{{NumFmtIdAttr: 170, FormatCodeAttr: "# ##0.00"}}
+	ss.X().NumFmts = nfs
+	SetNumberFormat(ss, sheet.Cell("B1"), "# ##0.00")
+	SetNumberFormat(ss, sheet.Cell("B2"), "# ##0.00")
+	if got := numFmtOf(ss, sheet.Cell("B2")); got != 170 || len(nfs.NumFmt) != 1 {
+		t.Errorf("existing format got id %d with %d formats, want 170 of 1", got, len(nfs.NumFmt))
+	}
+	SetNumberFormat(ss, sheet.Cell("B3"), "0.000")
+	SetNumberFormat(ss, sheet.Cell("B4"), "0.000")
+	if got := numFmtOf(ss, sheet.Cell("B4")); got != 200 || len(nfs.NumFmt) != 2 || *nfs.CountAttr != 2 {
+		t.Errorf("new format got id %d with %d formats, want 200 of 2", got, len(nfs.NumFmt))
+	}
+}
+
+func TestSetNumberFormatResave(t *testing.T) {
+	style := func(wb *spreadsheet.Workbook) {
+		sheet := wb.Sheets()[0]
+		for i := 1; i <= 20; i++ {
+			cell := sheet.Cell(fmt.Sprintf("A%d", i))
+			SetNumberFormat(wb.StyleSheet, cell, "# ##0.00 \"руб.\"")
+			SetNumberFormat(wb.StyleSheet, sheet.Cell(fmt.Sprintf("B%d", i)), "0.00")
+			FillColor(wb.StyleSheet, cell, RGBColor(color.Red))
+			FillCell(wb.StyleSheet, sheet.Cell(fmt.Sprintf("C%d", i)), SolidFill(ThemeColor(4, 0.4)))
+		}
+	}
+	counts := func(wb *spreadsheet.Workbook) [3]int {
+		x := wb.StyleSheet.X()
+		return [3]int{len(x.NumFmts.NumFmt), len(x.CellXfs.Xf), len(x.Fills.Fill)}
+	}
+	wb := spreadsheet.New()
+	wb.AddSheet()
+	style(wb)
+	want := counts(wb)
+	for i := 0; i < 3; i++ {
+		var buf bytes.Buffer
+		if err := wb.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if wb, err = spreadsheet.Read(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+			t.Fatal(err)
+		}
+		style(wb)
+		if got := counts(wb); got != want {
+			t.Errorf("round %d: %d number formats, %d styles and %d fills, want %v", i, got[0], got[1], got[2], want)
+		}
+	}
+}
+
+func TestCheckedHelpersBrokenStyleSheet(t *testing.T) {
+	tests := []struct {
+		name   string
+		break_ func(x *sml.StyleSheet, cell spreadsheet.Cell)
+	}{
+		{"no cell styles", func(x *sml.StyleSheet, cell spreadsheet.Cell) { x.CellXfs = nil }},
+		{"empty cell styles", func(x *sml.StyleSheet, cell spreadsheet.Cell) { x.CellXfs.Xf = nil }},
+		{"missing cell style", func(x *sml.StyleSheet, cell spreadsheet.Cell) { cell.SetStyleIndex(uint32(len(x.CellXfs.Xf))) }},
+		{"nil cell style", func(x *sml.StyleSheet, cell spreadsheet.Cell) {
+			x.CellXfs.Xf = append(x.CellXfs.Xf, nil)
+			cell.SetStyleIndex(uint32(len(x.CellXfs.Xf) - 1))
+		}},
+		{"missing font", func(x *sml.StyleSheet, cell spreadsheet.Cell) { x.Fonts = nil }},
+		{"missing fill", func(x *sml.StyleSheet, cell spreadsheet.Cell) {
+			x.CellXfs.Xf[*cell.X().SAttr].FillIdAttr = gooxml.Uint32(uint32(len(x.Fills.Fill)))
+		}},
+		{"missing border", func(x *sml.StyleSheet, cell spreadsheet.Cell) { x.Borders.Border = nil }},
+		{"missing number format", func(x *sml.StyleSheet, cell spreadsheet.Cell) { x.NumFmts = nil }},
+		{"nil number format", func(x *sml.StyleSheet, cell spreadsheet.Cell) {
+			x.NumFmts.NumFmt = append([]*sml.CT_NumFmt{nil}, x.NumFmts.NumFmt...)
+		}},
+	}
+	helpers := []struct {
+		name string
+		fn   func(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet) error
+	}{
+		{"SetNumberFormatChecked", func(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet) error {
+			_, err := SetNumberFormatChecked(ss, sheet.Cell("A1"), "0.0000")
+			return err
+		}},
+		{"ModifyCellStyleChecked", func(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet) error {
+			_, err := ModifyCellStyleChecked(ss, sheet.Cell("A1"), func(d *StyleDelta) { d.SetNumberFormat("0.0000") })
+			return err
+		}},
+		{"FillColorChecked", func(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet) error {
+			_, err := FillColorChecked(ss, sheet.Cell("A1"), RGBColor(color.Red))
+			return err
+		}},
+		{"SetNumberFormatRange", func(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet) error {
+			return SetNumberFormatRange(ss, sheet, "A1:B2", "0.0000")
+		}},
+		{"SetNumberFormatRange rows", func(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet) error {
+			return SetNumberFormatRange(ss, sheet, "1:1", "0.0000")
+		}},
+	}
+	for _, tt := range tests {
+		for _, h := range helpers {
+			wb := spreadsheet.New()
+			sheet := wb.AddSheet()
+			ss := wb.StyleSheet
+			cell := sheet.Cell("A1")
+			// the style of the cell has a custom number format
+			SetNumberFormat(ss, cell, "# ##0.000")
+			tt.break_(ss.X(), cell)
+			if tt.name == "nil number format" && h.name == "FillColorChecked" {
+				// the number formats aren't looked up
+				continue
+			}
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("%s: %s panics: %v", tt.name, h.name, r)
+					}
+				}()
+				if err := h.fn(ss, sheet); err == nil {
+					t.Errorf("%s: %s gives no error", tt.name, h.name)
+				}
+			}()
+		}
+	}
+}
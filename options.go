@@ -0,0 +1,124 @@
+package gooxmlhelpers
+
+import "strings"
+
+// MinorStyle - how minor currency units are written
+type MinorStyle int
+
+// MinorStyle constants
+const (
+	// MinorDigits - "Сто рублей 00 копеек"
+	MinorDigits MinorStyle = iota
+	// MinorWords - "Сто рублей ноль копеек"
+	MinorWords
+	// MinorOmitted - "Сто рублей"
+	MinorOmitted
+)
+
+// LetterCase - letter case of spelled amount
+type LetterCase int
+
+// LetterCase constants
+const (
+	// CaseFirst - only the first letter is upper case: "Сто рублей"
+	CaseFirst LetterCase = iota
+	// CaseLower - "сто рублей"
+	CaseLower
+	// CaseUpper - "СТО РУБЛЕЙ"
+	CaseUpper
+)
+
+// apply - change letter case of spelled text s
+func (c LetterCase) apply(s string) string {
+	switch c {
+	case CaseLower:
+		return strings.ToLower(s)
+	case CaseUpper:
+		return strings.ToUpper(s)
+	}
+	return capitalize(s)
+}
+
+// wrap - change letter case of spell formula f, first letter is capitalized by the formula
+func (c LetterCase) wrap(f string) string {
+	switch c {
+	case CaseLower:
+		return "LOWER(" + f + ")"
+	case CaseUpper:
+		return "UPPER(" + f + ")"
+	}
+	return f
+}
+
+// Dialect - spreadsheet applications the spell formulas are built for
+type Dialect int
+
+// Dialect constants
+const (
+	// DialectExcel - Excel, the vocabulary is kept in the defined names
+	DialectExcel Dialect = iota
+	// DialectPortable - Excel, LibreOffice Calc and Google Sheets. The formula has the
+	// vocabulary inline in CHOOSE and needs no defined names, it doesn't depend on the
+	// decimal separator and uses no 2-D array constants. Quantity formulas aren't built
+	// in it, see ErrPortableQuantity
+	DialectPortable
+)
+
+// GrammaticalCase - grammatical case of spelled amount, the oblique cases are supported
+// by Russian
+type GrammaticalCase int
+
+// GrammaticalCase constants
+const (
+	// Nominative - "сто двадцать три рубля"
+	Nominative GrammaticalCase = iota
+	// Genitive - "ста двадцати трех рублей"
+	Genitive
+	// Dative - "ста двадцати трем рублям"
+	Dative
+	// Instrumental - "ста двадцатью тремя рублями"
+	Instrumental
+	// Prepositional - "ста двадцати трех рублях"
+	Prepositional
+)
+
+// SpellOptions - language, currency and style of spelled amount, the zero value gives
+// Russian rubles in the default style: "Сто рублей 00 копеек"
+type SpellOptions struct {
+	// Language - nil is Russian
+	Language *Language
+	// Currency - zero value is RUB
+	Currency Currency
+	// Prefix - prefix of the defined names set by SetDefinedNamesPrefix
+	Prefix string
+
+	Minor MinorStyle
+	Case  LetterCase
+	// GramCase - grammatical case of the number and the currency names, it's used only for
+	// amounts
+	GramCase GrammaticalCase
+	// AbbrMajor, AbbrMinor - use abbreviated names of major and minor units: "руб.", "коп."
+	AbbrMajor bool
+	AbbrMinor bool
+	// Parentheses - put the amount in figures first and the words in parentheses after it:
+	// "100 (Сто) рублей 00 коп."
+	Parentheses bool
+	// Dialect - spreadsheet applications the formula is built for, ignored by Go spellers
+	Dialect Dialect
+}
+
+// language - language of the options, Russian by default
+func (o SpellOptions) language() *Language {
+	if o.Language == nil {
+		return Russian
+	}
+	return o.Language
+}
+
+// currency - currency of the options, RUB by default
+func (o SpellOptions) currency() Currency {
+	if o.Currency.Code == "" {
+		return RUB
+	}
+	return o.Currency
+}
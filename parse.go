@@ -0,0 +1,375 @@
+package gooxmlhelpers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// kinds of the words of amounts in words
+const (
+	wordNumber = iota
+	wordScale
+	wordMinus
+	wordSkip
+	wordMajor
+	wordMinor
+)
+
+// parsedWord - meaning of a word of amount in words
+type parsedWord struct {
+	kind  int
+	value uint64
+}
+
+// ParseRub - parse amount in words (Russian rubles) back to a number, see ParseAmount
+func ParseRub(text string) (float64, error) {
+	return ParseAmount(text, Russian, RUB)
+}
+
+// ParseAmount - parse amount in words in given language and currency back to a number:
+// "Сто двадцать три рубля 45 копеек" is 123.45. Any letter case, ё for е, extra spaces,
+// abbreviated units ("руб.", "коп.") and the grammatical cases are accepted, as well as
+// all the styles of SpellAmountOptions. In the style with parentheses the figures must
+// match the words
+func ParseAmount(text string, lang *Language, cur Currency) (float64, error) {
+	neg, units, cents, err := parseAmount(text, lang, cur)
+	if err != nil {
+		return 0, err
+	}
+	return amountValue(neg, units, cents), nil
+}
+
+// amountValue - the number closest to the amount of units and cents
+func amountValue(neg bool, units, cents uint64) float64 {
+	v, _ := strconv.ParseFloat(fmt.Sprintf("%d.%02d", units, cents), 64)
+	if neg {
+		return -v
+	}
+	return v
+}
+
+// parseAmount - parse amount in words to the sign, integer and fractional parts
+func parseAmount(text string, lang *Language, cur Currency) (neg bool, units, cents uint64, err error) {
+	dict := lang.parseDictionary(cur)
+	tokens := splitWords(text)
+	if len(tokens) == 0 {
+		return false, 0, 0, errors.New("empty text")
+	}
+	// "-100 (минус сто) рублей": the figures are checked against the words
+	figures := ""
+	if isFigures(tokens[0]) && len(tokens) > 1 && tokens[1] == "(" {
+		figures, tokens = tokens[0], tokens[2:]
+		i := indexOf(tokens, ")")
+		if i < 0 {
+			return false, 0, 0, errors.New("unbalanced parentheses")
+		}
+		tokens = append(tokens[:i:i], tokens[i+1:]...)
+	}
+	words, err := matchWords(tokens, dict)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	if len(words) > 0 && words[0].kind == wordMinus {
+		neg, words = true, words[1:]
+	}
+	// major part: number words and unit name
+	i := 0
+	for i < len(words) && (words[i].kind == wordNumber || words[i].kind == wordScale) {
+		i++
+	}
+	if i == 0 {
+		return false, 0, 0, errors.New("no number")
+	}
+	if units, err = wordsNumber(words[:i]); err != nil {
+		return false, 0, 0, err
+	}
+	if figures != "" && (strconv.FormatUint(units, 10) != strings.TrimPrefix(figures, "-") || neg != strings.HasPrefix(figures, "-")) {
+		return false, 0, 0, fmt.Errorf("figures %s don't match words", figures)
+	}
+	words = words[i:]
+	if len(words) > 0 && words[0].kind == wordMajor {
+		words = words[1:]
+	}
+	// minor part: figures or words and unit name
+	i = 0
+	for i < len(words) && words[i].kind == wordNumber {
+		i++
+	}
+	if cents, err = wordsNumber(words[:i]); err != nil {
+		return false, 0, 0, err
+	}
+	if cents > 99 {
+		return false, 0, 0, fmt.Errorf("%d minor units are more than a major one", cents)
+	}
+	words = words[i:]
+	if len(words) > 0 && words[0].kind == wordMinor {
+		words = words[1:]
+	}
+	if len(words) > 0 {
+		return false, 0, 0, errors.New("unexpected words after the amount")
+	}
+	return neg, units, cents, nil
+}
+
+// wordsNumber - sum up number words, parts of a group go from hundreds to ones and the
+// groups go from the biggest scale
+func wordsNumber(words []parsedWord) (uint64, error) {
+	var total, group uint64
+	limit, scaleLimit := uint64(1000), uint64(math.MaxUint64)
+	for _, w := range words {
+		if w.kind == wordScale {
+			if w.value >= scaleLimit {
+				return 0, errors.New("wrong order of thousands, millions")
+			}
+			if group == 0 {
+				group = 1
+			}
+			total += group * w.value
+			group, limit, scaleLimit = 0, 1000, w.value
+			continue
+		}
+		if w.value >= limit {
+			return 0, errors.New("wrong order of number words")
+		}
+		group += w.value
+		switch {
+		case w.value >= 100:
+			limit = 100
+		case w.value >= 20:
+			limit = 10
+		default:
+			limit = 1
+		}
+	}
+	return total + group, nil
+}
+
+// matchWords - meanings of tokens, the longest phrase of the dictionary is taken first
+func matchWords(tokens []string, dict map[string]parsedWord) ([]parsedWord, error) {
+	maxLen := 1
+	for k := range dict {
+		if n := strings.Count(k, " ") + 1; n > maxLen {
+			maxLen = n
+		}
+	}
+	var words []parsedWord
+	for i := 0; i < len(tokens); {
+		if isFigures(tokens[i]) {
+			// figures of minor units: "45 копеек"
+			n, err := strconv.ParseUint(tokens[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", tokens[i])
+			}
+			words = append(words, parsedWord{wordNumber, n})
+			i++
+			continue
+		}
+		n := maxLen
+		if i+n > len(tokens) {
+			n = len(tokens) - i
+		}
+		for ; n > 0; n-- {
+			if w, ok := dict[strings.Join(tokens[i:i+n], " ")]; ok {
+				if w.kind != wordSkip {
+					words = append(words, w)
+				}
+				break
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("unknown word %q", tokens[i])
+		}
+		i += n
+	}
+	return words, nil
+}
+
+// parseDictionary - meanings of the words of amounts in words in all the grammatical
+// cases of the language, the keys are normalized by normalizeWord
+func (l *Language) parseDictionary(cur Currency) map[string]parsedWord {
+	dict := map[string]parsedWord{}
+	add := func(w string, kind int, v uint64) {
+		w = normalizeWord(w)
+		if w == "" {
+			return
+		}
+		if _, ok := dict[w]; !ok {
+			dict[w] = parsedWord{kind, v}
+		}
+	}
+	for _, c := range []GrammaticalCase{Nominative, Genitive, Dative, Instrumental, Prepositional} {
+		w, ok := l.words(c)
+		if !ok {
+			continue
+		}
+		add(w.zero, wordNumber, 0)
+		for i := uint64(0); i < 10; i++ {
+			add(w.ones[i], wordNumber, i)
+			add(w.onesFem[i], wordNumber, i)
+			add(w.teens[i], wordNumber, 10+i)
+			add(w.tens[i], wordNumber, 10*i)
+			add(w.hundreds[i], wordNumber, 100*i)
+		}
+	}
+	scales := []struct {
+		u Unit
+		v uint64
+	}{{l.thousand, 1e3}, {l.million, 1e6}, {l.billion, 1e9}, {l.trillion, 1e12}}
+	for _, s := range scales {
+		for _, w := range l.unitForms(s.u) {
+			add(w, wordScale, s.v)
+		}
+	}
+	for _, w := range l.unitForms(cur.Major) {
+		add(w, wordMajor, 0)
+	}
+	for _, w := range l.unitForms(cur.Minor) {
+		add(w, wordMinor, 0)
+	}
+	add(l.minus, wordMinus, 0)
+	add(l.and, wordSkip, 0)
+	return dict
+}
+
+// unitForms - all the names of unit u: plural forms, declensions and abbreviation
+func (l *Language) unitForms(u Unit) []string {
+	forms := append(u.Forms[:], u.Abbr)
+	if d, ok := l.Declensions[u.Forms[0]]; ok {
+		forms = append(forms, d.Singular[:]...)
+		forms = append(forms, d.Plural[:]...)
+	}
+	return forms
+}
+
+// splitWords - normalized words, figures and parentheses of text
+func splitWords(text string) []string {
+	text = strings.NewReplacer("(", " ( ", ")", " ) ", "-", " - ").Replace(text)
+	var tokens []string
+	for _, f := range strings.Fields(text) {
+		if f == "-" {
+			// minus of figures or the separator of "twenty-one"
+			tokens = append(tokens, f)
+			continue
+		}
+		if w := normalizeWord(f); w != "" {
+			tokens = append(tokens, w)
+		}
+	}
+	// join minus to the figures after it, drop the other ones
+	var out []string
+	for i, t := range tokens {
+		switch {
+		case t != "-":
+			out = append(out, t)
+		case i+1 < len(tokens) && isFigures(tokens[i+1]):
+			tokens[i+1] = "-" + tokens[i+1]
+		}
+	}
+	return out
+}
+
+// normalizeWord - lower case word without punctuation around it and with е for ё, the
+// words of a phrase are separated by single spaces
+func normalizeWord(w string) string {
+	w = strings.Replace(strings.ToLower(w), "ё", "е", -1)
+	if w == "(" || w == ")" {
+		return w
+	}
+	fields := strings.FieldsFunc(w, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-'
+	})
+	for i, f := range fields {
+		fields[i] = strings.TrimFunc(f, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+		})
+	}
+	return strings.Join(fields, " ")
+}
+
+// isFigures - check if token is a number in figures, may be negative
+func isFigures(t string) bool {
+	t = strings.TrimPrefix(t, "-")
+	if t == "" {
+		return false
+	}
+	for _, r := range t {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// indexOf - index of s in tokens or -1
+func indexOf(tokens []string, s string) int {
+	for i, t := range tokens {
+		if t == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// SpellMismatch - pair of cells with amount and amount in words that don't match
+type SpellMismatch struct {
+	// Amount, Text - references of the cells
+	Amount string
+	Text   string
+	// Parsed - amount parsed from the text
+	Parsed float64
+	// Err - why the text can't be parsed, nil if it's parsed to another amount
+	Err error
+}
+
+// CheckSpelledAmounts - compare amounts in column amountCol of sheet with amounts in
+// words in column textCol of the same rows, in given language and currency. Rows with no
+// number in amountCol (headers) are skipped. The text of formulas is their cached result,
+// so recalculate them first (RecalculateFormulas)
+func CheckSpelledAmounts(sheet spreadsheet.Sheet, amountCol, textCol string, lang *Language, cur Currency) []SpellMismatch {
+	amountCol, textCol = strings.ToUpper(amountCol), strings.ToUpper(textCol)
+	var mismatches []SpellMismatch
+	for _, row := range sheet.Rows() {
+		var amount, text *spreadsheet.Cell
+		for _, c := range row.Cells() {
+			c := c
+			switch col, _ := c.Column(); col {
+			case amountCol:
+				amount = &c
+			case textCol:
+				text = &c
+			}
+		}
+		if amount == nil || amount.IsEmpty() || !amount.IsNumber() {
+			continue
+		}
+		v, err := amount.GetValueAsNumber()
+		if err != nil {
+			continue
+		}
+		m := SpellMismatch{Amount: amount.Reference(), Text: fmt.Sprintf("%s%d", textCol, row.RowNumber())}
+		if text == nil {
+			m.Err = errors.New("empty text")
+			mismatches = append(mismatches, m)
+			continue
+		}
+		neg, units, cents, err := parseAmount(text.GetString(), lang, cur)
+		if err != nil {
+			m.Err = err
+			mismatches = append(mismatches, m)
+			continue
+		}
+		wantUnits, wantCents, ok := splitAmount(math.Abs(v))
+		wantNeg := v < 0 && wantUnits+wantCents > 0
+		if !ok || units != wantUnits || cents != wantCents || neg != wantNeg && units+cents > 0 {
+			m.Parsed = amountValue(neg, units, cents)
+			mismatches = append(mismatches, m)
+		}
+	}
+	return mismatches
+}
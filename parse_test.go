@@ -0,0 +1,159 @@
+package gooxmlhelpers
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// parseAmounts - amounts spelled and parsed back by the round trip tests
+var parseAmounts = []float64{0, 0.01, 1, 2.02, 5, 11.11, 21.05, 112.5, 1000, 2000, -2000, 21000, 1001001.01,
+	5e6, 1234567.89, -0.5, 2e12 + 5, 999999999999999}
+
+func TestParseAmountRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts SpellOptions
+	}{
+		{"rub", SpellOptions{}},
+		{"usd", SpellOptions{Currency: USD}},
+		{"upper case", SpellOptions{Case: CaseUpper}},
+		{"lower case", SpellOptions{Case: CaseLower}},
+		{"abbreviations", SpellOptions{AbbrMajor: true, AbbrMinor: true}},
+		{"minor words", SpellOptions{Minor: MinorWords}},
+		{"parentheses", SpellOptions{Parentheses: true, AbbrMinor: true}},
+		{"genitive", SpellOptions{GramCase: Genitive}},
+		{"genitive minor words", SpellOptions{GramCase: Genitive, Minor: MinorWords}},
+		{"dative", SpellOptions{GramCase: Dative}},
+		{"dative minor words", SpellOptions{GramCase: Dative, Minor: MinorWords}},
+		{"instrumental", SpellOptions{GramCase: Instrumental, Parentheses: true}},
+		{"instrumental minor words", SpellOptions{GramCase: Instrumental, Minor: MinorWords}},
+		{"prepositional", SpellOptions{GramCase: Prepositional, Currency: USD}},
+		{"prepositional minor words", SpellOptions{GramCase: Prepositional, Minor: MinorWords}},
+		{"english", SpellOptions{Language: English, Currency: English.Currencies["USD"], Minor: MinorWords}},
+		{"ukrainian", SpellOptions{Language: Ukrainian, Currency: Ukrainian.Currencies["UAH"]}},
+		{"belarusian", SpellOptions{Language: Belarusian, Currency: Belarusian.Currencies["BYN"], Parentheses: true}},
+		{"kazakh", SpellOptions{Language: Kazakh, Currency: Kazakh.Currencies["KZT"], Minor: MinorWords}},
+	}
+	for _, tt := range tests {
+		for _, amount := range parseAmounts {
+			text := SpellAmountOptions(amount, tt.opts)
+			got, err := ParseAmount(text, tt.opts.language(), tt.opts.currency())
+			if err != nil {
+				t.Errorf("%s: ParseAmount(%q): %s", tt.name, text, err)
+				continue
+			}
+			if want := math.Round(amount*100) / 100; got != want {
+				t.Errorf("%s: ParseAmount(%q) = %v, want %v", tt.name, text, got, want)
+			}
+		}
+	}
+}
+
+func TestParseRub(t *testing.T) {
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"Сто двадцать три рубля 45 копеек", 123.45},
+		{"сТО ДВАДЦАТЬ три РУБЛЯ 45 копеек", 123.45},
+		{"  Сто   двадцать\tтри  рубля\n45   копеек. ", 123.45},
+		{"Четырёх рублей 00 копеек", 4},
+		{"Трёх тысяч рублей 05 копеек", 3000.05},
+		{"Сто руб. 50 коп.", 100.5},
+		{"Сто РУБ 50 КОП", 100.5},
+		{"100 (Сто) рублей 00 коп.", 100},
+		{"-5 (минус пять) рублей 00 копеек", -5},
+		{"Минус двадцать один рубль 01 копейка", -21.01},
+		{"Минус ноль рублей 50 копеек", -0.5},
+		{"Двадцати одного рубля 00 копеек", 21},
+		{"Двадцати одному рублю 02 копейкам", 21.02},
+		{"Двадцатью одним рублем 01 копейкой", 21.01},
+		{"Двадцати одном рубле 00 копеек", 21},
+		{"Двух миллионах рублей 02 копейках", 2e6 + 0.02},
+		{"Одной тысячей рублей", 1000},
+		{"Пятьсот рублей", 500},
+		{"Тысяча рублей", 1000},
+		{"Один миллион одна тысяча один рубль 01 копейка", 1001001.01},
+		{"Два триллиона пять рублей 00 копеек", 2e12 + 5},
+	}
+	for _, tt := range tests {
+		got, err := ParseRub(tt.text)
+		if err != nil {
+			t.Errorf("ParseRub(%q): %s", tt.text, err)
+		} else if got != tt.want {
+			t.Errorf("ParseRub(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseRubInvalid(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"   ",
+		"рублей 45 копеек",
+		"сто сто рублей",
+		"двадцать тридцать рублей",
+		"пять двадцать рублей",
+		"тысяча миллион рублей",
+		"сто рублей сто копеек",
+		"сто долларов",
+		"сто рублей 45 копеек рублей",
+		"сто рублей 45 копеек сверху",
+		"100 (двести) рублей 00 копеек",
+		"-100 (сто) рублей 00 копеек",
+		"100 (сто рублей 00 копеек",
+		"сто 1e5 рублей",
+	} {
+		if got, err := ParseRub(text); err == nil {
+			t.Errorf("ParseRub(%q) = %v, want error", text, got)
+		}
+	}
+}
+
+func TestCheckSpelledAmounts(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	sheet.Cell("A1").SetString("Сумма")
+	sheet.Cell("B1").SetString("Прописью")
+	// matching text and formula
+	sheet.Cell("A2").SetNumber(123.45)
+	sheet.Cell("B2").SetString("Сто двадцать три рубля 45 копеек")
+	sheet.Cell("A3").SetNumber(-21.01)
+	if err := SetSpellValue(sheet, sheet.Cell("B3"), "A3"); err != nil {
+		t.Fatal(err)
+	}
+	// other amount, unknown words and no text
+	sheet.Cell("A4").SetNumber(124.45)
+	sheet.Cell("B4").SetString("сто двадцать три рубля 45 копеек")
+	sheet.Cell("A5").SetNumber(5)
+	sheet.Cell("B5").SetString("пять долларов")
+	sheet.Cell("A6").SetNumber(7)
+	// sign and rounding
+	sheet.Cell("A7").SetNumber(8)
+	sheet.Cell("B7").SetString("Минус восемь рублей 00 копеек")
+	sheet.Cell("A8").SetNumber(0.004)
+	sheet.Cell("B8").SetString("Ноль рублей 00 копеек")
+
+	got := CheckSpelledAmounts(sheet, "a", "b", Russian, RUB)
+	want := []SpellMismatch{
+		{Amount: "A4", Text: "B4", Parsed: 123.45},
+		{Amount: "A5", Text: "B5"},
+		{Amount: "A6", Text: "B6"},
+		{Amount: "A7", Text: "B7", Parsed: -8},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CheckSpelledAmounts = %+v, want %d mismatches", got, len(want))
+	}
+	for i := range want {
+		if (got[i].Err != nil) != (want[i].Parsed == 0) {
+			t.Errorf("mismatch %d error %v", i, got[i].Err)
+		}
+		got[i].Err = nil
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("mismatch %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
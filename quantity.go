@@ -0,0 +1,129 @@
+package gooxmlhelpers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+// Built-in units of quantity with Russian names
+var (
+	Piece    = Unit{Feminine, [3]string{"штука", "штуки", "штук"}, "шт."}
+	Kilogram = Unit{Masculine, [3]string{"килограмм", "килограмма", "килограммов"}, "кг"}
+	Tonne    = Unit{Feminine, [3]string{"тонна", "тонны", "тонн"}, "т"}
+	Litre    = Unit{Masculine, [3]string{"литр", "литра", "литров"}, "л"}
+	Metre    = Unit{Masculine, [3]string{"метр", "метра", "метров"}, "м"}
+)
+
+// SpellQuantity - return quantity of unit in words: "Двенадцать штук", "Одна тонна".
+// Decimals are rounded to thousandths and spelled as fraction: "Две целых пять десятых
+// литра". Negative quantities start with minus. Only opts.Language and opts.Case are used.
+// Like amounts it supports absolute values up to 999 999 999 999 999.999, for others an
+// empty string is returned
+func SpellQuantity(q float64, unit Unit, opts SpellOptions) string {
+	lang := opts.language()
+	n, frac, ok := splitDecimal(math.Abs(q), 3)
+	if !ok {
+		return ""
+	}
+	var words []string
+	if q < 0 && n+frac > 0 {
+		words = append(words, lang.minus)
+	}
+	if frac == 0 {
+		words = append(words, lang.spellNumber(n, unit.Gender), unit.Forms[lang.pluralForm(n)])
+		return opts.Case.apply(strings.Join(words, " "))
+	}
+	// 0.500 is five tenths
+	den := 2
+	for ; frac%10 == 0; frac /= 10 {
+		den--
+	}
+	d := lang.fractions[den]
+	fraction := []string{lang.spellNumber(frac, d.Gender), d.Forms[lang.pluralForm(frac)]}
+	if lang.fracFirst {
+		fraction[0], fraction[1] = fraction[1], fraction[0]
+	}
+	words = append(words, lang.spellNumber(n, lang.whole.Gender), lang.whole.Forms[lang.pluralForm(n)])
+	words = append(words, fraction...)
+	words = append(words, unit.Forms[lang.fracForm])
+	return opts.Case.apply(strings.Join(words, " "))
+}
+
+// ErrPortableQuantity - quantity formulas aren't built in DialectPortable, with the
+// vocabulary inline they are longer than Excel allows
+var ErrPortableQuantity = errors.New("quantity formula can't be built in the portable dialect: it's longer than Excel allows")
+
+// GetSpellQuantityFormula - return formula giving the same text as SpellQuantity for ref,
+// the defined names must be set for opts.Language and opts.Prefix. Quantities out of range
+// give the #NUM! error like GetSpellFormulaOptions. For DialectPortable ErrPortableQuantity
+// is returned
+func GetSpellQuantityFormula(ref string, unit Unit, opts SpellOptions) (string, error) {
+	if opts.Dialect == DialectPortable {
+		return "", ErrPortableQuantity
+	}
+	ref, err := checkSpellRef(ref, opts.Prefix)
+	if err != nil {
+		return "", err
+	}
+	f := quantityFormula(ref, unit, opts)
+	if err := checkFormula(f); err != nil {
+		return "", fmt.Errorf("can't build quantity formula for %q: %s", ref, err)
+	}
+	if err := checkFormulaLen(f); err != nil {
+		return "", fmt.Errorf("can't build quantity formula for %q: %w", ref, err)
+	}
+	return f, nil
+}
+
+// SetSpellQuantityFormula - convert ref cell value(number) to quantity of unit in words
+func SetSpellQuantityFormula(cell spreadsheet.Cell, ref string, unit Unit, opts SpellOptions) error {
+	f, err := GetSpellQuantityFormula(ref, unit, opts)
+	if err != nil {
+		return err
+	}
+	cell.SetFormulaRaw(f)
+	return nil
+}
+
+// quantityFormula - build formula spelling ref as quantity of unit
+func quantityFormula(ref string, unit Unit, opts SpellOptions) string {
+	lang := opts.language()
+	opts.GramCase = Nominative
+	b := newSpellBuilder(ref, 3, opts)
+	// thousandths, reduced to hundredths or tenths when possible
+	frac := b.decimal(1, 3)
+	num := fmt.Sprintf("%s/IF(MOD(%s,10),1,IF(MOD(%s,100),10,100))", frac, frac, frac)
+	den := fmt.Sprintf("IF(MOD(%s,10),3,IF(MOD(%s,100),2,1))", frac, frac)
+
+	// ones of the integer part agree with the unit or with the "whole" word
+	integer := b.integer(b.negative(), func(t, u string) string {
+		return b.tensIf(frac, lang.whole.Gender, unit.Gender, t, u)
+	}, opts.Case)
+
+	numWords := []string{b.hundreds(fmt.Sprintf("INT(%s/100)", num))}
+	if lang.and != "" {
+		numWords = append(numWords, fmt.Sprintf(`IF(AND(INT(%[1]s/100),MOD(%[1]s,100)),%[2]s,"")`,
+			num, formulaString(encodeWord(lang.and)+"z")))
+	}
+	numWords = append(numWords, b.tens(lang.fractions[0].Gender,
+		fmt.Sprintf("MOD(INT(%s/10),10)", num), fmt.Sprintf("MOD(%s,10)", num)))
+	fraction := []string{
+		"TRIM(" + lang.decodeWords(strings.Join(numWords, "&")) + ")",
+		fmt.Sprintf("IF(%s=1,%s,IF(%s=2,%s,%s))", den,
+			b.plural(lang.fractions[0], num, num), den,
+			b.plural(lang.fractions[1], num, num),
+			b.plural(lang.fractions[2], num, num)),
+	}
+	if lang.fracFirst {
+		fraction[0], fraction[1] = fraction[1], fraction[0]
+	}
+	f := fmt.Sprintf(`%s&IF(%s,%s&" "&%s&" "&%s&" "&%s,%s)`, integer, frac,
+		b.plural(lang.whole, b.mid(spellDigits-1, 2), b.whole()), fraction[0], fraction[1],
+		formulaString(unit.Forms[lang.fracForm]),
+		b.plural(unit, b.mid(spellDigits-1, 2), b.whole()))
+	return "=" + b.rangeCheck(opts.Case.wrap(f))
+}
@@ -0,0 +1,52 @@
+package gooxmlhelpers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestSpellQuantityFormula(t *testing.T) {
+	quantities := []float64{0, 1, 2.5, 21, 0.125, -1000.01, 2e6}
+	tests := []struct {
+		unit Unit
+		opts SpellOptions
+	}{
+		{Piece, SpellOptions{}},
+		{Litre, SpellOptions{Case: CaseUpper}},
+		{Tonne, SpellOptions{Language: English}},
+		{Metre, SpellOptions{Language: Ukrainian, Prefix: "Q"}},
+	}
+	for _, tt := range tests {
+		wb := spreadsheet.New()
+		sheet := wb.AddSheet()
+		if err := SetDefinedNamesPrefix(wb, tt.opts.language(), tt.opts.Prefix); err != nil {
+			t.Fatal(err)
+		}
+		for i, q := range quantities {
+			ref := fmt.Sprintf("A%d", i+1)
+			sheet.Cell(ref).SetNumber(q)
+			if err := SetSpellQuantityFormula(sheet.Cell(fmt.Sprintf("B%d", i+1)), ref, tt.unit, tt.opts); err != nil {
+				t.Fatalf("SetSpellQuantityFormula(%s): %s", tt.unit.Forms[0], err)
+			}
+		}
+		RecalculateFormulas(wb)
+		for i, q := range quantities {
+			got := sheet.Cell(fmt.Sprintf("B%d", i+1)).GetString()
+			if want := SpellQuantity(q, tt.unit, tt.opts); got != want {
+				t.Errorf("quantity formula of %v %s = %q, want %q", q, tt.unit.Forms[0], got, want)
+			}
+		}
+	}
+}
+
+func TestSpellQuantityFormulaPortable(t *testing.T) {
+	for _, lang := range []*Language{Russian, Kazakh} {
+		_, err := GetSpellQuantityFormula("A1", Piece, SpellOptions{Language: lang, Dialect: DialectPortable})
+		if !errors.Is(err, ErrPortableQuantity) {
+			t.Errorf("%s: error %v, want ErrPortableQuantity", lang.Code, err)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package gooxmlhelpers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSpellAmount is the first amount that does not fit into the 15 integer
+// digits of the N_0 format used by the spell formula, it's also the limit of
+// Excel precision
+const maxSpellAmount = 1e15
+
+// SpellRub - return amount in words (Russian rubles), the same text as the
+// GetSpellFormula formula gives: "Сто двадцать три рубля 45 копеек".
+// Negative amounts start with minus: "Минус пять рублей 00 копеек". The amount
+// is rounded to kopecks, like the formula it supports absolute values up to
+// 999 999 999 999 999.99, for bigger amounts an empty string is returned
+func SpellRub(amount float64) string {
+	return SpellCurrency(amount, RUB)
+}
+
+// SpellCurrency - return amount in words in given currency, the same text as the
+// GetSpellFormulaCurrency formula gives. See SpellRub for supported amounts
+func SpellCurrency(amount float64, cur Currency) string {
+	return SpellAmount(amount, Russian, cur)
+}
+
+// SpellAmount - return amount in words in given language and currency, the same text as
+// the GetSpellFormulaLanguage formula gives. See SpellRub for supported amounts
+func SpellAmount(amount float64, lang *Language, cur Currency) string {
+	return SpellAmountOptions(amount, SpellOptions{Language: lang, Currency: cur})
+}
+
+// SpellAmountOptions - return amount in words in the style set by opts, the same text as
+// the GetSpellFormulaOptions formula gives. See SpellRub for supported amounts, an empty
+// string is returned as well if the language can't spell the currency in opts.GramCase
+func SpellAmountOptions(amount float64, opts SpellOptions) string {
+	lang, cur := opts.language(), opts.currency()
+	units, cents, ok := splitAmount(math.Abs(amount))
+	if !ok || lang.checkCase(opts.GramCase, cur) != nil {
+		return ""
+	}
+	c := opts.GramCase
+	text := lang.spellNumberCase(units, cur.Major.Gender, c)
+	sign := ""
+	if amount < 0 && units+cents > 0 {
+		text, sign = lang.minus+" "+text, "-"
+	}
+	if opts.Parentheses {
+		text = fmt.Sprintf("%s%d (%s)", sign, units, opts.Case.apply(text))
+	}
+	text += " " + lang.unitName(cur.Major, units, c, opts.AbbrMajor)
+	switch opts.Minor {
+	case MinorDigits:
+		text += fmt.Sprintf(" %02d %s", cents, lang.unitName(cur.Minor, cents, c, opts.AbbrMinor))
+	case MinorWords:
+		text += fmt.Sprintf(" %s %s", lang.spellNumberCase(cents, cur.Minor.Gender, c), lang.unitName(cur.Minor, cents, c, opts.AbbrMinor))
+	}
+	return opts.Case.apply(text)
+}
+
+// spellNumber - return n in words, zero included
+func (l *Language) spellNumber(n uint64, gender Gender) string {
+	return l.spellNumberCase(n, gender, Nominative)
+}
+
+// spellNumberCase - return n in words in case c, zero included
+func (l *Language) spellNumberCase(n uint64, gender Gender, c GrammaticalCase) string {
+	if n == 0 {
+		w, _ := l.words(c)
+		return w.zero
+	}
+	return strings.Join(l.numberWords(n, gender, c), " ")
+}
+
+// splitAmount - round amount to kopecks the way Excel TEXT does and split it
+// into integer and fractional parts
+func splitAmount(amount float64) (units, cents uint64, ok bool) {
+	return splitDecimal(amount, 2)
+}
+
+// splitDecimal - round v to given number of decimals the way Excel TEXT does and split it
+// into integer part and the decimals as integer, v must not be negative
+func splitDecimal(v float64, decimals int) (units, frac uint64, ok bool) {
+	if v < 0 || v >= maxSpellAmount || math.IsNaN(v) {
+		return 0, 0, false
+	}
+	scale := uint64(1)
+	for i := 0; i < decimals; i++ {
+		scale *= 10
+	}
+	// Excel keeps 15 significant digits and rounds half away from zero
+	s := strconv.FormatFloat(v, 'e', 14, 64)
+	mant, exp := s[:strings.IndexByte(s, 'e')], s[strings.IndexByte(s, 'e')+1:]
+	digits, _ := strconv.ParseUint(strings.Replace(mant, ".", "", 1), 10, 64)
+	e, _ := strconv.Atoi(exp)
+	// v*scale == digits * 10^(e+decimals-14)
+	shift := 14 - decimals - e
+	var n uint64
+	switch {
+	case shift <= 0:
+		for n = digits; shift < 0; shift++ {
+			n *= 10
+		}
+	case shift > 18:
+		n = 0
+	default:
+		div := uint64(1)
+		for i := 0; i < shift; i++ {
+			div *= 10
+		}
+		n = digits / div
+		if digits%div*2 >= div {
+			n++
+		}
+	}
+	if n >= maxSpellAmount*scale {
+		return 0, 0, false
+	}
+	return n / scale, n % scale, true
+}
+
+// numberWords - spell n in case c, ones of the last group agree with gender
+func (l *Language) numberWords(n uint64, gender Gender, c GrammaticalCase) []string {
+	w, _ := l.words(c)
+	var words []string
+	groups := [...]struct {
+		n     uint64
+		scale *Unit
+	}{
+		{n / 1e12 % 1000, &l.trillion},
+		{n / 1e9 % 1000, &l.billion},
+		{n / 1e6 % 1000, &l.million},
+		{n / 1e3 % 1000, &l.thousand},
+		{n % 1000, nil},
+	}
+	for _, g := range groups {
+		if g.scale == nil {
+			// "one thousand and five"
+			and := l.and != "" && g.n > 0 && g.n < 100 && n >= 1000
+			if and {
+				words = append(words, l.and)
+			}
+			words = l.appendTriple(words, g.n, gender == Feminine, w)
+			break
+		}
+		if g.n == 0 {
+			continue
+		}
+		words = l.appendTriple(words, g.n, g.scale.Gender == Feminine, w)
+		name, _ := l.caseForm(*g.scale, g.n, c)
+		words = append(words, name)
+	}
+	return words
+}
+
+// appendTriple - append words w for number from 0 to 999
+func (l *Language) appendTriple(words []string, n uint64, feminine bool, w caseWords) []string {
+	ones := w.ones
+	if feminine {
+		ones = w.onesFem
+	}
+	h, t, u := n/100, n/10%10, n%10
+	if h > 0 {
+		words = append(words, w.hundreds[h])
+		if l.and != "" && n%100 > 0 {
+			words = append(words, l.and)
+		}
+	}
+	switch {
+	case t == 1:
+		words = append(words, w.teens[u])
+	case t > 1 && u > 0 && l.tensSep != "":
+		words = append(words, w.tens[t]+l.tensSep+ones[u])
+	case t > 1:
+		words = append(words, w.tens[t])
+		fallthrough
+	default:
+		if u > 0 {
+			words = append(words, ones[u])
+		}
+	}
+	return words
+}
+
+// pluralForm - index in Unit.Forms to use after n
+func pluralForm(n uint64) int {
+	switch {
+	case n%100 >= 11 && n%100 <= 19:
+		return 2
+	case n%10 == 1:
+		return 0
+	case n%10 >= 2 && n%10 <= 4:
+		return 1
+	}
+	return 2
+}
+
+// capitalize - make first letter upper case
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
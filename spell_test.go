@@ -0,0 +1,87 @@
+package gooxmlhelpers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSpellRub(t *testing.T) {
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		// zero and kopecks
+		{0, "Ноль рублей 00 копеек"},
+		{0.01, "Ноль рублей 01 копейка"},
+		{0.02, "Ноль рублей 02 копейки"},
+		{0.05, "Ноль рублей 05 копеек"},
+		{0.11, "Ноль рублей 11 копеек"},
+		{0.21, "Ноль рублей 21 копейка"},
+		{0.004, "Ноль рублей 00 копеек"},
+		{0.005, "Ноль рублей 01 копейка"},
+		{1.005, "Один рубль 01 копейка"},
+		{123.45, "Сто двадцать три рубля 45 копеек"},
+
+		// agreement with 1, 2 and 5
+		{1, "Один рубль 00 копеек"},
+		{2, "Два рубля 00 копеек"},
+		{4, "Четыре рубля 00 копеек"},
+		{5, "Пять рублей 00 копеек"},
+		{21, "Двадцать один рубль 00 копеек"},
+		{22, "Двадцать два рубля 00 копеек"},
+		{25, "Двадцать пять рублей 00 копеек"},
+		{101, "Сто один рубль 00 копеек"},
+
+		// teens
+		{10, "Десять рублей 00 копеек"},
+		{11, "Одиннадцать рублей 00 копеек"},
+		{12, "Двенадцать рублей 00 копеек"},
+		{13, "Тринадцать рублей 00 копеек"},
+		{14, "Четырнадцать рублей 00 копеек"},
+		{111, "Сто одиннадцать рублей 00 копеек"},
+		{112.12, "Сто двенадцать рублей 12 копеек"},
+		{1014, "Одна тысяча четырнадцать рублей 00 копеек"},
+
+		// thousands and millions
+		{1000, "Одна тысяча рублей 00 копеек"},
+		{2000, "Две тысячи рублей 00 копеек"},
+		{5000, "Пять тысяч рублей 00 копеек"},
+		{11000, "Одиннадцать тысяч рублей 00 копеек"},
+		{21000, "Двадцать одна тысяча рублей 00 копеек"},
+		{212000, "Двести двенадцать тысяч рублей 00 копеек"},
+		{1000000, "Один миллион рублей 00 копеек"},
+		{2000000, "Два миллиона рублей 00 копеек"},
+		{5000000, "Пять миллионов рублей 00 копеек"},
+		{1001001.01, "Один миллион одна тысяча один рубль 01 копейка"},
+		{13014000, "Тринадцать миллионов четырнадцать тысяч рублей 00 копеек"},
+		{1e9, "Один миллиард рублей 00 копеек"},
+		{2e12, "Два триллиона рублей 00 копеек"},
+
+		// the limit
+		{999999999999999, "Девятьсот девяносто девять триллионов девятьсот девяносто девять миллиардов " +
+			"девятьсот девяносто девять миллионов девятьсот девяносто девять тысяч девятьсот девяносто " +
+			"девять рублей 00 копеек"},
+		{1e15, ""},
+		{999999999999999.99, ""},
+		{1e20, ""},
+		{math.NaN(), ""},
+		{math.Inf(1), ""},
+
+		// negatives
+		{-5, "Минус пять рублей 00 копеек"},
+		{-21.01, "Минус двадцать один рубль 01 копейка"},
+		{-0.5, "Минус ноль рублей 50 копеек"},
+		{-0.004, "Ноль рублей 00 копеек"},
+		{-2000, "Минус две тысячи рублей 00 копеек"},
+		{-999999999999999, "Минус девятьсот девяносто девять триллионов девятьсот девяносто девять миллиардов " +
+			"девятьсот девяносто девять миллионов девятьсот девяносто девять тысяч девятьсот девяносто " +
+			"девять рублей 00 копеек"},
+		{-1e15, ""},
+		{math.Inf(-1), ""},
+	}
+	for _, tt := range tests {
+		if got := SpellRub(tt.amount); got != tt.want {
+			t.Errorf("SpellRub(%v) = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
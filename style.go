@@ -0,0 +1,1059 @@
+package gooxmlhelpers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"baliance.com/gooxml"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+	"baliance.com/gooxml/spreadsheet/reference"
+)
+
+// Styler - style helpers of a stylesheet sharing the indexes of its cell styles, fonts,
+// fills, borders and number formats, so the stylesheet isn't indexed again for every cell.
+// The package functions make a Styler per call, keep one to style many cells one by one.
+// It's freed with the workbook and isn't safe for concurrent use
+type Styler struct {
+	ss spreadsheet.StyleSheet
+	c  styleCache
+}
+
+// NewStyler - Styler of stylesheet ss
+func NewStyler(ss spreadsheet.StyleSheet) *Styler {
+	return &Styler{ss: ss}
+}
+
+// styleCache - indexes of the cell styles, fonts, fills, borders and number formats of a stylesheet
+type styleCache struct {
+	xfs     contentIndex
+	fonts   contentIndex
+	fills   contentIndex
+	borders contentIndex
+	numFmts contentIndex
+}
+
+// contentIndex - positions of the elements of a stylesheet list by their XML content.
+// The list may be changed by other code, so a found position is checked against the
+// list and the elements appended since the last lookup are indexed on a miss
+type contentIndex struct {
+	pos     map[string]int
+	scanned int
+}
+
+// find - position of the element with content key in a list of n elements, keyAt gives
+// the content of an element
+func (ci *contentIndex) find(key string, n int, keyAt func(i int) (string, error)) (int, bool, error) {
+	i, ok := ci.pos[key]
+	if ok && i < n {
+		k, err := keyAt(i)
+		if err != nil {
+			return 0, false, err
+		}
+		if k == key {
+			return i, true, nil
+		}
+	}
+	if ok || ci.pos == nil || ci.scanned > n {
+		// the list was changed in place, index it again
+		ci.pos, ci.scanned = map[string]int{}, 0
+	}
+	for ; ci.scanned < n; ci.scanned++ {
+		k, err := keyAt(ci.scanned)
+		if err != nil {
+			return 0, false, err
+		}
+		if _, ok := ci.pos[k]; !ok {
+			ci.pos[k] = ci.scanned
+		}
+	}
+	i, ok = ci.pos[key]
+	return i, ok, nil
+}
+
+// add - remember that the element with content key is at position i
+func (ci *contentIndex) add(key string, i int) {
+	if ci.scanned == i {
+		ci.pos[key] = i
+		ci.scanned++
+	}
+}
+
+// contentKey - XML content of a stylesheet element
+func contentKey(v xml.Marshaler) (string, error) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("can't compare %T: %s", v, err)
+	}
+	return string(b), nil
+}
+
+// styleNamespaces - namespaces of the prefixes used by stylesheet elements
+var styleNamespaces = []xml.Attr{
+	{Name: xml.Name{Local: "xmlns"}, Value: "http://schemas.openxmlformats.org/spreadsheetml/2006/main"},
+	{Name: xml.Name{Local: "xmlns:ma"}, Value: "http://schemas.openxmlformats.org/spreadsheetml/2006/main"},
+	{Name: xml.Name{Local: "xmlns:r"}, Value: "http://schemas.openxmlformats.org/officeDocument/2006/relationships"},
+	{Name: xml.Name{Local: "xmlns:s"}, Value: "http://schemas.openxmlformats.org/officeDocument/2006/sharedTypes"},
+}
+
+// cloneXML - deep copy of stylesheet element src to dst, the element is written with the
+// namespaces of its children, so they are read back
+func cloneXML(src xml.Marshaler, dst xml.Unmarshaler) error {
+	var buf bytes.Buffer
+	e := xml.NewEncoder(&buf)
+	start := xml.StartElement{Name: xml.Name{Local: "ma:e"}, Attr: append([]xml.Attr{}, styleNamespaces...)}
+	err := src.MarshalXML(e, start)
+	if err == nil {
+		err = e.Flush()
+	}
+	if err == nil {
+		err = xml.Unmarshal(buf.Bytes(), dst)
+	}
+	if err != nil {
+		return fmt.Errorf("can't copy %T: %s", src, err)
+	}
+	return nil
+}
+
+// cellXf - copy of the cell style of cell, a new style if the cell has none or its style
+// doesn't exist
+func cellXf(ss spreadsheet.StyleSheet, cell spreadsheet.Cell) (*sml.CT_Xf, error) {
+	switch {
+	case ss.X() == nil:
+		return nil, errors.New("empty stylesheet")
+	case cell.X() == nil:
+		return nil, errors.New("empty cell")
+	}
+	return styleXf(ss, cell.X().SAttr)
+}
+
+// styleXf - copy of cell style s of ss, a new style if s is nil or doesn't exist
+func styleXf(ss spreadsheet.StyleSheet, s *uint32) (*sml.CT_Xf, error) {
+	xf := sml.NewCT_Xf()
+	xfs := ss.X().CellXfs
+	if s != nil && xfs != nil && int(*s) < len(xfs.Xf) && xfs.Xf[*s] != nil {
+		if err := cloneXML(xfs.Xf[*s], xf); err != nil {
+			return nil, err
+		}
+	}
+	return xf, nil
+}
+
+// index - position of element v in a list of n elements, add appends the element when
+// the list has no identical one
+func (ci *contentIndex) index(v xml.Marshaler, n int, elemAt func(i int) xml.Marshaler, add func()) (uint32, error) {
+	key, err := contentKey(v)
+	if err != nil {
+		return 0, err
+	}
+	i, ok, err := ci.find(key, n, func(i int) (string, error) { return contentKey(elemAt(i)) })
+	if err != nil || ok {
+		return uint32(i), err
+	}
+	add()
+	ci.add(key, n)
+	return uint32(n), nil
+}
+
+// styleIndex - index of cell style xf in ss, an identical existing style is reused
+func (c *styleCache) styleIndex(ss spreadsheet.StyleSheet, xf *sml.CT_Xf) (uint32, error) {
+	if ss.X().CellXfs == nil {
+		ss.X().CellXfs = sml.NewCT_CellXfs()
+	}
+	xfs := ss.X().CellXfs
+	return c.xfs.index(xf, len(xfs.Xf),
+		func(i int) xml.Marshaler { return xfs.Xf[i] },
+		func() {
+			xfs.Xf = append(xfs.Xf, xf)
+			xfs.CountAttr = gooxml.Uint32(uint32(len(xfs.Xf)))
+		})
+}
+
+// fillIndex - index of fill f in ss, an identical existing fill is reused
+func (c *styleCache) fillIndex(ss spreadsheet.StyleSheet, f *sml.CT_Fill) (uint32, error) {
+	if ss.X().Fills == nil {
+		ss.X().Fills = sml.NewCT_Fills()
+	}
+	fills := ss.X().Fills
+	return c.fills.index(f, len(fills.Fill),
+		func(i int) xml.Marshaler { return fills.Fill[i] },
+		func() {
+			fills.Fill = append(fills.Fill, f)
+			fills.CountAttr = gooxml.Uint32(uint32(len(fills.Fill)))
+		})
+}
+
+// fontIndex - index of font f in ss, an identical existing font is reused
+func (c *styleCache) fontIndex(ss spreadsheet.StyleSheet, f *sml.CT_Font) (uint32, error) {
+	if ss.X().Fonts == nil {
+		ss.X().Fonts = sml.NewCT_Fonts()
+	}
+	fonts := ss.X().Fonts
+	return c.fonts.index(f, len(fonts.Font),
+		func(i int) xml.Marshaler { return fonts.Font[i] },
+		func() {
+			fonts.Font = append(fonts.Font, f)
+			fonts.CountAttr = gooxml.Uint32(uint32(len(fonts.Font)))
+		})
+}
+
+// borderIndex - index of border b in ss, an identical existing border is reused
+func (c *styleCache) borderIndex(ss spreadsheet.StyleSheet, b *sml.CT_Border) (uint32, error) {
+	if ss.X().Borders == nil {
+		ss.X().Borders = sml.NewCT_Borders()
+	}
+	borders := ss.X().Borders
+	return c.borders.index(b, len(borders.Border),
+		func(i int) xml.Marshaler { return borders.Border[i] },
+		func() {
+			borders.Border = append(borders.Border, b)
+			borders.CountAttr = gooxml.Uint32(uint32(len(borders.Border)))
+		})
+}
+
+// builtinNumFmts - ids of the built-in number formats by code, the dates and times
+// (14-22) are shown in the format of the user's locale, so they aren't used for the codes
+var builtinNumFmts = func() map[string]uint32 {
+	ids := map[string]uint32{}
+	for id := spreadsheet.StandardFormat(0); id <= spreadsheet.StandardFormat49; id++ {
+		if id >= spreadsheet.StandardFormat14 && id <= spreadsheet.StandardFormat22 {
+			continue
+		}
+		// unknown ids get the code of General
+		if code := spreadsheet.CreateDefaultNumberFormat(id).X().FormatCodeAttr; code != "General" || id == 0 {
+			ids[code] = uint32(id)
+		}
+	}
+	return ids
+}()
+
+// numFmtID - id of number format code in ss: a built-in format, an existing format with
+// the same code or a new custom one
+func (c *styleCache) numFmtID(ss spreadsheet.StyleSheet, code string) (uint32, error) {
+	if id, ok := builtinNumFmts[code]; ok {
+		return id, nil
+	}
+	if ss.X().NumFmts == nil {
+		ss.X().NumFmts = sml.NewCT_NumFmts()
+	}
+	nfs := ss.X().NumFmts
+	keyAt := func(i int) (string, error) {
+		if nfs.NumFmt[i] == nil {
+			return "", fmt.Errorf("number format %d of the stylesheet is empty", i)
+		}
+		return nfs.NumFmt[i].FormatCodeAttr, nil
+	}
+	i, ok, err := c.numFmts.find(code, len(nfs.NumFmt), keyAt)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		return nfs.NumFmt[i].NumFmtIdAttr, nil
+	}
+	// custom formats start at 164, gooxml starts its ones at 200
+	id := uint32(199)
+	for _, nf := range nfs.NumFmt {
+		if nf.NumFmtIdAttr > id {
+			id = nf.NumFmtIdAttr
+		}
+	}
+	nf := sml.NewCT_NumFmt()
+	nf.NumFmtIdAttr = id + 1
+	nf.FormatCodeAttr = code
+	nfs.NumFmt = append(nfs.NumFmt, nf)
+	nfs.CountAttr = gooxml.Uint32(uint32(len(nfs.NumFmt)))
+	c.numFmts.add(code, len(nfs.NumFmt)-1)
+	return nf.NumFmtIdAttr, nil
+}
+
+// BorderSide - sides of a cell border set by StyleDelta.SetBorder, may be combined
+type BorderSide int
+
+// BorderSide constants
+const (
+	BorderLeft BorderSide = 1 << iota
+	BorderRight
+	BorderTop
+	BorderBottom
+	BorderAll = BorderLeft | BorderRight | BorderTop | BorderBottom
+)
+
+// StyleDelta - changes of a cell style made by ModifyCellStyle, the attributes that aren't
+// changed keep their values of the current style of the cell. The font and the border of
+// the cell are copied on the first change, so other cells using them aren't affected
+type StyleDelta struct {
+	ss     spreadsheet.StyleSheet
+	xf     *sml.CT_Xf
+	font   *sml.CT_Font
+	border *sml.CT_Border
+	fill   *sml.CT_Fill
+	numFmt *string
+	err    error
+}
+
+// setErr - remember the first error of the changes
+func (d *StyleDelta) setErr(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// fontX - font of the new style, a copy of the current font
+func (d *StyleDelta) fontX() *sml.CT_Font {
+	if d.font == nil {
+		d.font = sml.NewCT_Font()
+		// a style without font uses the first one
+		id := uint32(0)
+		if d.xf.FontIdAttr != nil {
+			id = *d.xf.FontIdAttr
+		}
+		if fonts := d.ss.X().Fonts; fonts != nil && int(id) < len(fonts.Font) && fonts.Font[id] != nil {
+			d.setErr(cloneXML(fonts.Font[id], d.font))
+		}
+	}
+	return d.font
+}
+
+// borderX - border of the new style, a copy of the current border
+func (d *StyleDelta) borderX() *sml.CT_Border {
+	if d.border == nil {
+		d.border = sml.NewCT_Border()
+		id := uint32(0)
+		if d.xf.BorderIdAttr != nil {
+			id = *d.xf.BorderIdAttr
+		}
+		if borders := d.ss.X().Borders; borders != nil && int(id) < len(borders.Border) && borders.Border[id] != nil {
+			d.setErr(cloneXML(borders.Border[id], d.border))
+		}
+	}
+	return d.border
+}
+
+// alignment - alignment of the new style
+func (d *StyleDelta) alignment() *sml.CT_CellAlignment {
+	if d.xf.Alignment == nil {
+		d.xf.Alignment = sml.NewCT_CellAlignment()
+	}
+	d.xf.ApplyAlignmentAttr = gooxml.Bool(true)
+	return d.xf.Alignment
+}
+
+// protection - protection of the new style
+func (d *StyleDelta) protection() *sml.CT_CellProtection {
+	if d.xf.Protection == nil {
+		d.xf.Protection = sml.NewCT_CellProtection()
+	}
+	d.xf.ApplyProtectionAttr = gooxml.Bool(true)
+	return d.xf.Protection
+}
+
+// SetBold - set or clear bold font
+func (d *StyleDelta) SetBold(b bool) {
+	if b {
+		d.fontX().B = []*sml.CT_BooleanProperty{{}}
+	} else {
+		d.fontX().B = nil
+	}
+}
+
+// SetItalic - set or clear italic font
+func (d *StyleDelta) SetItalic(b bool) {
+	if b {
+		d.fontX().I = []*sml.CT_BooleanProperty{{}}
+	} else {
+		d.fontX().I = nil
+	}
+}
+
+// SetFontSize - set font size in points
+func (d *StyleDelta) SetFontSize(size float64) {
+	d.fontX().Sz = []*sml.CT_FontSize{{ValAttr: size}}
+}
+
+// SetFontColor - set font color, the zero color makes it automatic
+func (d *StyleDelta) SetFontColor(clr Color) {
+	if clr.IsZero() {
+		d.fontX().Color = nil
+	} else {
+		d.fontX().Color = []*sml.CT_Color{clr.x()}
+	}
+}
+
+// SetFontName - set font name ("Arial")
+func (d *StyleDelta) SetFontName(name string) {
+	d.fontX().Name = []*sml.CT_FontName{{ValAttr: name}}
+}
+
+// SetHorizontalAlignment - set horizontal alignment of the text
+func (d *StyleDelta) SetHorizontalAlignment(a sml.ST_HorizontalAlignment) {
+	d.alignment().HorizontalAttr = a
+}
+
+// SetVerticalAlignment - set vertical alignment of the text
+func (d *StyleDelta) SetVerticalAlignment(a sml.ST_VerticalAlignment) {
+	d.alignment().VerticalAttr = a
+}
+
+// SetWrapped - set or clear text wrapping
+func (d *StyleDelta) SetWrapped(b bool) {
+	if b {
+		d.alignment().WrapTextAttr = gooxml.Bool(true)
+	} else {
+		d.alignment().WrapTextAttr = nil
+	}
+}
+
+// SetIndent - set text indent in steps of three spaces, 0 clears it
+func (d *StyleDelta) SetIndent(n uint32) {
+	if n > 0 {
+		d.alignment().IndentAttr = gooxml.Uint32(n)
+	} else {
+		d.alignment().IndentAttr = nil
+	}
+}
+
+// SetRotation - set text rotation: 0-90 degrees counterclockwise, 91-180 are 1-90 degrees
+// clockwise, 255 is vertical text
+func (d *StyleDelta) SetRotation(r uint8) {
+	if r > 0 {
+		d.alignment().TextRotationAttr = gooxml.Uint8(r)
+	} else {
+		d.alignment().TextRotationAttr = nil
+	}
+}
+
+// SetBorder - set border sides of the cell to style and color, sml.ST_BorderStyleNone
+// removes them
+func (d *StyleDelta) SetBorder(sides BorderSide, style sml.ST_BorderStyle, clr Color) {
+	b := d.borderX()
+	for _, s := range []struct {
+		side BorderSide
+		pr   **sml.CT_BorderPr
+	}{{BorderLeft, &b.Left}, {BorderRight, &b.Right}, {BorderTop, &b.Top}, {BorderBottom, &b.Bottom}} {
+		if sides&s.side == 0 {
+			continue
+		}
+		pr := sml.NewCT_BorderPr()
+		pr.StyleAttr = style
+		if style != sml.ST_BorderStyleNone && style != sml.ST_BorderStyleUnset {
+			pr.Color = clr.x()
+		}
+		*s.pr = pr
+	}
+}
+
+// SetFillColor - fill the cell with solid color
+func (d *StyleDelta) SetFillColor(clr Color) {
+	d.fill = SolidFill(clr).fill()
+}
+
+// SetNumberFormat - set number format code ("# ##0.00")
+func (d *StyleDelta) SetNumberFormat(format string) {
+	d.numFmt = &format
+}
+
+// SetLocked - set or clear cell lock, it works when the sheet is protected
+func (d *StyleDelta) SetLocked(b bool) {
+	d.protection().LockedAttr = gooxml.Bool(b)
+}
+
+// SetHidden - set or clear hiding of the formula, it works when the sheet is protected
+func (d *StyleDelta) SetHidden(b bool) {
+	d.protection().HiddenAttr = gooxml.Bool(b)
+}
+
+// ModifyCellStyle - change the style of cell by fn keeping the other attributes of its
+// current style, returns the style index of the cell. Identical styles, fonts, fills,
+// borders and number formats are shared by the cells. If the style can't be changed, the
+// error is logged by gooxml.Log, the cell is left as is and 0 is returned. 0 is the index
+// of the default style as well, so the result doesn't tell a failure: use
+// ModifyCellStyleChecked or Styler.ModifyCellStyle when it matters
+func ModifyCellStyle(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, fn func(*StyleDelta)) uint32 {
+	idx, err := NewStyler(ss).modifyCellStyle(cell, fn)
+	if err != nil {
+		gooxml.Log("can't change cell style: %s", err)
+		return 0
+	}
+	return idx
+}
+
+// modifyCellStyle - change the style of cell by fn, see ModifyCellStyle
+func (s *Styler) modifyCellStyle(cell spreadsheet.Cell, fn func(*StyleDelta)) (uint32, error) {
+	xf, err := cellXf(s.ss, cell)
+	if err != nil {
+		return 0, err
+	}
+	idx, err := s.applyStyleDelta(xf, fn)
+	if err != nil {
+		return 0, err
+	}
+	cell.SetStyleIndex(idx)
+	return idx, nil
+}
+
+// applyStyleDelta - change style xf by fn and return the index of the changed style
+func (s *Styler) applyStyleDelta(xf *sml.CT_Xf, fn func(*StyleDelta)) (uint32, error) {
+	ss, c := s.ss, &s.c
+	d := &StyleDelta{ss: ss, xf: xf}
+	fn(d)
+	if d.err != nil {
+		return 0, d.err
+	}
+	if d.font != nil {
+		id, err := c.fontIndex(ss, d.font)
+		if err != nil {
+			return 0, err
+		}
+		d.xf.FontIdAttr = gooxml.Uint32(id)
+		d.xf.ApplyFontAttr = gooxml.Bool(true)
+	}
+	if d.border != nil {
+		id, err := c.borderIndex(ss, d.border)
+		if err != nil {
+			return 0, err
+		}
+		d.xf.BorderIdAttr = gooxml.Uint32(id)
+		d.xf.ApplyBorderAttr = gooxml.Bool(true)
+	}
+	if d.fill != nil {
+		id, err := c.fillIndex(ss, d.fill)
+		if err != nil {
+			return 0, err
+		}
+		d.xf.FillIdAttr = gooxml.Uint32(id)
+		d.xf.ApplyFillAttr = gooxml.Bool(true)
+	}
+	if d.numFmt != nil {
+		id, err := c.numFmtID(ss, *d.numFmt)
+		if err != nil {
+			return 0, err
+		}
+		d.xf.NumFmtIdAttr = gooxml.Uint32(id)
+		d.xf.ApplyNumberFormatAttr = gooxml.Bool(true)
+	}
+	return c.styleIndex(ss, d.xf)
+}
+
+// cellRange - rectangle of cells, columns are 0-based and rows are 1-based as in
+// reference.CellReference
+type cellRange struct {
+	fromCol, toCol, fromRow, toRow uint32
+}
+
+// parseCellRange - range "B5:H40" or a single cell "B5", the corners may be in any order
+func parseCellRange(ref string) (cellRange, error) {
+	from, to, err := reference.ParseRangeReference(ref)
+	if err != nil {
+		if from, err = reference.ParseCellReference(ref); err != nil {
+			return cellRange{}, fmt.Errorf("invalid range %q: %s", ref, err)
+		}
+		to = from
+	}
+	r := cellRange{from.ColumnIdx, to.ColumnIdx, from.RowIdx, to.RowIdx}
+	if r.fromCol > r.toCol {
+		r.fromCol, r.toCol = r.toCol, r.fromCol
+	}
+	if r.fromRow > r.toRow {
+		r.fromRow, r.toRow = r.toRow, r.fromRow
+	}
+	return r, nil
+}
+
+// intersects - check if ranges r and o have common cells
+func (r cellRange) intersects(o cellRange) bool {
+	return r.fromCol <= o.toCol && o.fromCol <= r.toCol && r.fromRow <= o.toRow && o.fromRow <= r.toRow
+}
+
+// rangeCells - cells of range ref of sheet and of the merged areas intersecting it,
+// missing cells are created
+func rangeCells(sheet spreadsheet.Sheet, ref string) ([]spreadsheet.Cell, error) {
+	r, err := parseCellRange(ref)
+	if err != nil {
+		return nil, err
+	}
+	return areaCells(sheet, append([]cellRange{r}, mergedAreas(sheet, r)...), nil), nil
+}
+
+// mergedAreas - merged areas of sheet intersecting range r
+func mergedAreas(sheet spreadsheet.Sheet, r cellRange) []cellRange {
+	var areas []cellRange
+	for _, m := range sheet.MergedCells() {
+		mr, err := parseCellRange(m.Reference())
+		if err == nil && mr.intersects(r) {
+			areas = append(areas, mr)
+		}
+	}
+	return areas
+}
+
+// areaCells - cells of areas of sheet but those skip reports (nil skips none), missing
+// cells are created
+func areaCells(sheet spreadsheet.Sheet, areas []cellRange, skip func(row, col uint32) bool) []spreadsheet.Cell {
+	var cells []spreadsheet.Cell
+	seen := map[[2]uint32]bool{}
+	for _, a := range areas {
+		for row := a.fromRow; row <= a.toRow; row++ {
+			sr := sheet.Row(row)
+			n := len(sr.X().C)
+			for col := a.fromCol; col <= a.toCol; col++ {
+				if seen[[2]uint32{row, col}] || skip != nil && skip(row, col) {
+					continue
+				}
+				seen[[2]uint32{row, col}] = true
+				cells = append(cells, sr.Cell(reference.IndexToColumn(col)))
+			}
+			if len(sr.X().C) != n {
+				sortRowCells(sr)
+			}
+		}
+	}
+	return cells
+}
+
+// sortRowCells - put the cells of row in the column order, Excel repairs a file with new
+// cells added after the existing ones
+func sortRowCells(row spreadsheet.Row) {
+	col := func(c *sml.CT_Cell) uint32 {
+		if c.RAttr == nil {
+			return 0
+		}
+		ref, err := reference.ParseCellReference(*c.RAttr)
+		if err != nil {
+			return 0
+		}
+		return ref.ColumnIdx
+	}
+	cs := row.X().C
+	sort.SliceStable(cs, func(i, j int) bool { return col(cs[i]) < col(cs[j]) })
+}
+
+// Sheet limits of Excel
+const (
+	maxRows    = 1048576
+	maxColumns = 16384
+)
+
+// lineRange - whole rows or whole columns, 1-based as CT_Row.RAttr and CT_Col.MinAttr
+type lineRange struct {
+	columns  bool
+	from, to uint32
+}
+
+// cells - the cells of the lines as a cell range
+func (l lineRange) cells() cellRange {
+	if l.columns {
+		return cellRange{l.from - 1, l.to - 1, 1, maxRows}
+	}
+	return cellRange{0, maxColumns - 1, l.from, l.to}
+}
+
+// has - check if the lines have cell of column col (0-based) in row
+func (l lineRange) has(row, col uint32) bool {
+	if l.columns {
+		return l.from <= col+1 && col+1 <= l.to
+	}
+	return l.from <= row && row <= l.to
+}
+
+// parseLineRange - whole rows ("5:7", "$5:$7") or whole columns ("B:D") of range ref, ok
+// is false for other references
+func parseLineRange(ref string) (lines lineRange, ok bool, err error) {
+	parts := strings.Split(strings.Replace(ref, "$", "", -1), ":")
+	if len(parts) != 2 {
+		return lineRange{}, false, nil
+	}
+	isDigits := func(s string) bool { return s != "" && strings.Trim(s, "0123456789") == "" }
+	isLetters := func(s string) bool {
+		return s != "" && strings.Trim(strings.ToUpper(s), "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == ""
+	}
+	var n [2]uint64
+	switch {
+	case isDigits(parts[0]) && isDigits(parts[1]):
+		for i, p := range parts {
+			if n[i], err = strconv.ParseUint(p, 10, 32); err != nil || n[i] < 1 || n[i] > maxRows {
+				return lineRange{}, true, fmt.Errorf("invalid range %q: no row %s", ref, p)
+			}
+		}
+	case isLetters(parts[0]) && isLetters(parts[1]):
+		lines.columns = true
+		for i, p := range parts {
+			if len(p) > 3 || reference.ColumnToIndex(strings.ToUpper(p)) >= maxColumns {
+				return lineRange{}, true, fmt.Errorf("invalid range %q: no column %s", ref, p)
+			}
+			n[i] = uint64(reference.ColumnToIndex(strings.ToUpper(p))) + 1
+		}
+	default:
+		return lineRange{}, false, nil
+	}
+	if n[0] > n[1] {
+		n[0], n[1] = n[1], n[0]
+	}
+	lines.from, lines.to = uint32(n[0]), uint32(n[1])
+	return lines, true, nil
+}
+
+// modifyLinesStyle - change the style of the rows or columns of lines of sheet by fn, see
+// ModifyRangeStyle. The existing cells of the lines and the cells of merged areas
+// intersecting them are changed as well. Rows with a style of their own get the cells of
+// the columns, so the row style doesn't hide the column one
+func (s *Styler) modifyLinesStyle(sheet spreadsheet.Sheet, lines lineRange, fn func(*StyleDelta)) error {
+	if err := checkLinesStyle(s.ss, sheet, lines); err != nil {
+		return err
+	}
+	if lines.columns {
+		for _, col := range columnEntries(sheet, lines.from, lines.to) {
+			idx, err := s.modifyStyleIndex(col.StyleAttr, fmt.Sprintf("column %s", reference.IndexToColumn(col.MinAttr-1)), fn)
+			if err != nil {
+				return err
+			}
+			col.StyleAttr = gooxml.Uint32(idx)
+		}
+	} else {
+		for r := lines.from; r <= lines.to; r++ {
+			row := sheet.Row(r).X()
+			st := row.SAttr
+			if row.CustomFormatAttr == nil || !*row.CustomFormatAttr {
+				st = nil
+			}
+			idx, err := s.modifyStyleIndex(st, fmt.Sprintf("row %d", r), fn)
+			if err != nil {
+				return err
+			}
+			row.SAttr, row.CustomFormatAttr = gooxml.Uint32(idx), gooxml.Bool(true)
+		}
+	}
+	var cells []spreadsheet.Cell
+	for _, sr := range sheet.Rows() {
+		row := sr.X()
+		if row.RAttr == nil {
+			continue
+		}
+		if lines.columns && row.SAttr != nil && row.CustomFormatAttr != nil && *row.CustomFormatAttr {
+			n := len(row.C)
+			for col := lines.from - 1; col < lines.to; col++ {
+				if c := sr.Cell(reference.IndexToColumn(col)); c.X().SAttr == nil {
+					c.SetStyleIndex(*row.SAttr)
+				}
+			}
+			if len(row.C) != n {
+				sortRowCells(sr)
+			}
+		}
+		for _, c := range sr.Cells() {
+			if cr, err := reference.ParseCellReference(c.Reference()); err == nil && lines.has(cr.RowIdx, cr.ColumnIdx) {
+				cells = append(cells, c)
+			}
+		}
+	}
+	merged := areaCells(sheet, mergedAreas(sheet, lines.cells()), lines.has)
+	for _, c := range append(cells, merged...) {
+		if _, err := s.ModifyCellStyle(c, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLinesStyle - check the styles changed by modifyLinesStyle before any of them is
+// changed, the new styles could make missing ones exist
+func checkLinesStyle(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet, lines lineRange) error {
+	if err := checkStyleSheet(ss); err != nil {
+		return err
+	}
+	x := ss.X()
+	if lines.columns {
+		for _, cs := range sheet.X().Cols {
+			for _, c := range cs.Col {
+				if c.MaxAttr < lines.from || c.MinAttr > lines.to {
+					continue
+				}
+				if err := checkStyleIndex(x, c.StyleAttr, "column "+reference.IndexToColumn(c.MinAttr-1)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	var cells []spreadsheet.Cell
+	for _, sr := range sheet.Rows() {
+		row := sr.X()
+		custom := row.CustomFormatAttr != nil && *row.CustomFormatAttr
+		// the style of a row is copied to the cells of the columns
+		if custom && (lines.columns || lines.has(sr.RowNumber(), 0)) {
+			if err := checkStyleIndex(x, row.SAttr, fmt.Sprintf("row %d", sr.RowNumber())); err != nil {
+				return err
+			}
+		}
+		for _, c := range sr.Cells() {
+			if cr, err := reference.ParseCellReference(c.Reference()); err == nil && lines.has(cr.RowIdx, cr.ColumnIdx) {
+				cells = append(cells, c)
+			}
+		}
+	}
+	for _, c := range append(cells, areaCells(sheet, mergedAreas(sheet, lines.cells()), lines.has)...) {
+		if err := checkCellStyle(ss, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modifyStyleIndex - index of cell style st of owner changed by fn, see checkStyleIndex
+func (s *Styler) modifyStyleIndex(st *uint32, owner string, fn func(*StyleDelta)) (uint32, error) {
+	if err := checkStyleIndex(s.ss.X(), st, owner); err != nil {
+		return 0, err
+	}
+	xf, err := styleXf(s.ss, st)
+	if err != nil {
+		return 0, err
+	}
+	return s.applyStyleDelta(xf, fn)
+}
+
+// defaultColumnWidth - width of the columns added by the style helpers when the sheet has
+// no default, the width Excel writes for the columns of the default font
+const defaultColumnWidth = 9.140625
+
+// columnEntries - col elements of sheet for columns from to (1-based), one per column:
+// the elements spanning other columns too are split and missing ones are added with the
+// default width, a col element without width hides the column. The col elements of the
+// sheet are kept in the column order
+func columnEntries(sheet spreadsheet.Sheet, from, to uint32) []*sml.CT_Col {
+	x := sheet.X()
+	var cols []*sml.CT_Col
+	entries := make([]*sml.CT_Col, to-from+1)
+	part := func(c *sml.CT_Col, min, max uint32) *sml.CT_Col {
+		p := *c
+		p.MinAttr, p.MaxAttr = min, max
+		cols = append(cols, &p)
+		return &p
+	}
+	for _, cs := range x.Cols {
+		for _, c := range cs.Col {
+			if c.MaxAttr < from || c.MinAttr > to {
+				cols = append(cols, c)
+				continue
+			}
+			if c.MinAttr < from {
+				part(c, c.MinAttr, from-1)
+			}
+			for i := maxUint32(c.MinAttr, from); i <= minUint32(c.MaxAttr, to); i++ {
+				entries[i-from] = part(c, i, i)
+			}
+			if c.MaxAttr > to {
+				part(c, to+1, c.MaxAttr)
+			}
+		}
+	}
+	width := defaultColumnWidth
+	if f := x.SheetFormatPr; f != nil && f.DefaultColWidthAttr != nil {
+		width = *f.DefaultColWidthAttr
+	}
+	for i := range entries {
+		if entries[i] == nil {
+			c := sml.NewCT_Col()
+			c.MinAttr, c.MaxAttr, c.WidthAttr = from+uint32(i), from+uint32(i), gooxml.Float64(width)
+			cols = append(cols, c)
+			entries[i] = c
+		}
+	}
+	sort.SliceStable(cols, func(i, j int) bool { return cols[i].MinAttr < cols[j].MinAttr })
+	cs := sml.NewCT_Cols()
+	cs.Col = cols
+	x.Cols = []*sml.CT_Cols{cs}
+	return entries
+}
+
+// minUint32 - the smaller of a and b
+func minUint32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// maxUint32 - the bigger of a and b
+func maxUint32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ModifyRangeStyle - change the styles of the cells of range ref ("B5:H40") of sheet by
+// fn like ModifyCellStyle, every cell keeps the other attributes of its own style. The
+// cells of merged areas intersecting the range are changed as well, so borders and fills
+// of the areas render whole. Missing cells are created. The first broken cell style stops
+// the changes, see ModifyCellStyleChecked.
+// For whole rows ("5:5", "5:7") and columns ("B:B", "B:D") the style of the rows or
+// columns is changed instead, only the existing cells of them are changed as well
+func ModifyRangeStyle(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet, ref string, fn func(*StyleDelta)) error {
+	return NewStyler(ss).ModifyRangeStyle(sheet, ref, fn)
+}
+
+// ModifyRangeStyle - same as the ModifyRangeStyle function for the stylesheet of s
+func (s *Styler) ModifyRangeStyle(sheet spreadsheet.Sheet, ref string, fn func(*StyleDelta)) error {
+	if lines, ok, err := parseLineRange(ref); ok {
+		if err != nil {
+			return err
+		}
+		return s.modifyLinesStyle(sheet, lines, fn)
+	}
+	cells, err := rangeCells(sheet, ref)
+	if err != nil {
+		return err
+	}
+	// the new styles could make missing ones of the next cells exist
+	for _, c := range cells {
+		if err := checkCellStyle(s.ss, c); err != nil {
+			return err
+		}
+	}
+	for _, c := range cells {
+		if _, err := s.ModifyCellStyle(c, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FillColorRange - fill the cells of range ref of sheet with color like FillColor, see
+// ModifyRangeStyle
+func FillColorRange(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet, ref string, clr Color) error {
+	return ModifyRangeStyle(ss, sheet, ref, func(d *StyleDelta) {
+		d.SetFillColor(clr)
+	})
+}
+
+// SetNumberFormatRange - set number format to the cells of range ref of sheet like
+// SetNumberFormat, see ModifyRangeStyle. An invalid format is refused, see
+// ValidateNumberFormat
+func SetNumberFormatRange(ss spreadsheet.StyleSheet, sheet spreadsheet.Sheet, ref string, format string) error {
+	if err := ValidateNumberFormat(format); err != nil {
+		return err
+	}
+	return ModifyRangeStyle(ss, sheet, ref, func(d *StyleDelta) {
+		d.SetNumberFormat(format)
+	})
+}
+
+// checkCellStyle - check that the style of cell can be changed: the stylesheet has cell
+// styles, the style index of the cell and the font, fill, border and custom number format
+// of its style exist
+func checkCellStyle(ss spreadsheet.StyleSheet, cell spreadsheet.Cell) error {
+	if err := checkStyleSheet(ss); err != nil {
+		return err
+	}
+	if cell.X() == nil {
+		return errors.New("empty cell")
+	}
+	return checkStyleIndex(ss.X(), cell.X().SAttr, "cell "+cell.Reference())
+}
+
+// checkStyleSheet - check that the stylesheet has cell styles
+func checkStyleSheet(ss spreadsheet.StyleSheet) error {
+	x := ss.X()
+	switch {
+	case x == nil:
+		return errors.New("empty stylesheet")
+	case x.CellXfs == nil || len(x.CellXfs.Xf) == 0:
+		return errors.New("stylesheet has no cell styles")
+	}
+	return nil
+}
+
+// checkStyleIndex - check that style s of owner ("cell B5", "row 5") and the font, fill,
+// border and custom number format of the style exist in stylesheet x with cell styles, nil
+// s is the default style
+func checkStyleIndex(x *sml.StyleSheet, s *uint32, owner string) error {
+	if s == nil {
+		return nil
+	}
+	if int(*s) >= len(x.CellXfs.Xf) {
+		return fmt.Errorf("%s has style %d, but there are %d styles", owner, *s, len(x.CellXfs.Xf))
+	}
+	xf := x.CellXfs.Xf[*s]
+	if xf == nil {
+		return fmt.Errorf("%s has style %d, but it's empty", owner, *s)
+	}
+	for _, p := range []struct {
+		name string
+		id   *uint32
+		n    int
+	}{
+		{"font", xf.FontIdAttr, func() int {
+			if x.Fonts == nil {
+				return 0
+			}
+			return len(x.Fonts.Font)
+		}()},
+		{"fill", xf.FillIdAttr, func() int {
+			if x.Fills == nil {
+				return 0
+			}
+			return len(x.Fills.Fill)
+		}()},
+		{"border", xf.BorderIdAttr, func() int {
+			if x.Borders == nil {
+				return 0
+			}
+			return len(x.Borders.Border)
+		}()},
+	} {
+		// a missing id is the first one
+		id := uint32(0)
+		if p.id != nil {
+			id = *p.id
+		}
+		if int(id) >= p.n {
+			return fmt.Errorf("style %d of %s has %s %d, but there are %d of them", *s, owner, p.name, id, p.n)
+		}
+	}
+	if id := xf.NumFmtIdAttr; id != nil && *id >= firstCustomNumFmt && !hasNumFmt(x, *id) {
+		return fmt.Errorf("style %d of %s has number format %d, but it isn't defined", *s, owner, *id)
+	}
+	return nil
+}
+
+// firstCustomNumFmt - the first id of the number formats defined by the stylesheet
+const firstCustomNumFmt = 164
+
+// hasNumFmt - check if stylesheet x defines number format id
+func hasNumFmt(x *sml.StyleSheet, id uint32) bool {
+	if x.NumFmts == nil {
+		return false
+	}
+	for _, nf := range x.NumFmts.NumFmt {
+		if nf != nil && nf.NumFmtIdAttr == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ModifyCellStyleChecked - same as ModifyCellStyle, but returns an error instead of
+// guessing when the stylesheet or the style of the cell is broken, see checkCellStyle.
+// It's safe for any workbook read by spreadsheet.Open
+func ModifyCellStyleChecked(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, fn func(*StyleDelta)) (uint32, error) {
+	return NewStyler(ss).ModifyCellStyle(cell, fn)
+}
+
+// ModifyCellStyle - same as ModifyCellStyleChecked for the stylesheet of s
+func (s *Styler) ModifyCellStyle(cell spreadsheet.Cell, fn func(*StyleDelta)) (uint32, error) {
+	if err := checkCellStyle(s.ss, cell); err != nil {
+		return 0, err
+	}
+	return s.modifyCellStyle(cell, fn)
+}
+
+// FillColorChecked - same as FillColor, but returns an error when the stylesheet or the
+// style of the cell is broken, see ModifyCellStyleChecked
+func FillColorChecked(ss spreadsheet.StyleSheet, cell spreadsheet.Cell, clr Color) (uint32, error) {
+	return ModifyCellStyleChecked(ss, cell, func(d *StyleDelta) {
+		d.SetFillColor(clr)
+	})
+}
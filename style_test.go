@@ -0,0 +1,333 @@
+package gooxmlhelpers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"testing"
+
+	"baliance.com/gooxml/color"
+	"baliance.com/gooxml/schema/soo/sml"
+	"baliance.com/gooxml/spreadsheet"
+)
+
+func TestModifyCellStyleReuse(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	ss := wb.StyleSheet
+	red := FillColor(ss, sheet.Cell("A1"), RGBColor(color.Red))
+	if got := FillColor(ss, sheet.Cell("A2"), RGBColor(color.Red)); got != red {
+		t.Errorf("same fill got style %d, want %d", got, red)
+	}
+	n := len(ss.X().CellXfs.Xf)
+	blue := FillColor(ss, sheet.Cell("A3"), RGBColor(color.Blue))
+	if blue == red || len(ss.X().CellXfs.Xf) != n+1 {
+		t.Errorf("other fill got style %d of %d, want a new one", blue, len(ss.X().CellXfs.Xf))
+	}
+	bold := ModifyCellStyle(ss, sheet.Cell("A1"), func(d *StyleDelta) { d.SetBold(true) })
+	if got := ModifyCellStyle(ss, sheet.Cell("A2"), func(d *StyleDelta) { d.SetBold(true) }); got != bold {
+		t.Errorf("same change got style %d, want %d", got, bold)
+	}
+}
+
+func TestStyler(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	ss := wb.StyleSheet
+	st := NewStyler(ss)
+	fill := func(c Color) func(*StyleDelta) {
+		return func(d *StyleDelta) { d.SetFillColor(c) }
+	}
+	n := len(ss.X().CellXfs.Xf)
+	red, err := st.ModifyCellStyle(sheet.Cell("A1"), fill(RGBColor(color.Red)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 2; i <= 50; i++ {
+		c := RGBColor(color.Red)
+		if i%2 == 0 {
+			c = RGBColor(color.Blue)
+		}
+		if _, err := st.ModifyCellStyle(sheet.Cell(fmt.Sprintf("A%d", i)), fill(c)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(ss.X().CellXfs.Xf) != n+2 {
+		t.Errorf("%d styles, want %d", len(ss.X().CellXfs.Xf), n+2)
+	}
+	// the styles added by the package functions and other code are found by the Styler
+	green := FillColor(ss, sheet.Cell("B1"), RGBColor(color.Green))
+	if got, _ := st.ModifyCellStyle(sheet.Cell("B2"), fill(RGBColor(color.Green))); got != green {
+		t.Errorf("style added by FillColor: got %d, want %d", got, green)
+	}
+	if got := FillColor(ss, sheet.Cell("B3"), RGBColor(color.Red)); got != red {
+		t.Errorf("style added by the Styler: FillColor got %d, want %d", got, red)
+	}
+	// other code replaces a style in place
+	ss.X().CellXfs.Xf[red] = sml.NewCT_Xf()
+	got, err := st.ModifyCellStyle(sheet.Cell("B4"), fill(RGBColor(color.Red)))
+	if err != nil || got == red {
+		t.Errorf("replaced style is reused: %d, %v", got, err)
+	}
+	if err := st.ModifyRangeStyle(sheet, "C1:C10", fill(RGBColor(color.Green))); err != nil {
+		t.Fatal(err)
+	}
+	if got := *sheet.Cell("C10").X().SAttr; got != green {
+		t.Errorf("range style %d, want %d", got, green)
+	}
+}
+
+// failingMarshaler - stylesheet element which can't be written
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return errors.New("broken element")
+}
+
+func TestContentIndexError(t *testing.T) {
+	var ci contentIndex
+	elems := []xml.Marshaler{failingMarshaler{}}
+	at := func(i int) xml.Marshaler { return elems[i] }
+	added := false
+	if _, err := ci.index(failingMarshaler{}, 0, at, func() { added = true }); err == nil || added {
+		t.Errorf("broken new element: error %v, added %v", err, added)
+	}
+	if _, err := ci.index(SolidFill(RGBColor(color.Red)).fill(), len(elems), at, func() { added = true }); err == nil || added {
+		t.Errorf("broken existing element: error %v, added %v", err, added)
+	}
+}
+
+func TestModifyCellStyleBroken(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	ss := wb.StyleSheet
+	bold := func(d *StyleDelta) { d.SetBold(true) }
+
+	// the style of the cell doesn't exist, a new one is made
+	cell := sheet.Cell("A1")
+	cell.SetStyleIndex(1000)
+	if idx := ModifyCellStyle(ss, cell, bold); int(idx) >= len(ss.X().CellXfs.Xf) {
+		t.Errorf("style %d of %d", idx, len(ss.X().CellXfs.Xf))
+	}
+	// missing font and border elements aren't copied
+	xf := ss.X().CellXfs.Xf[0]
+	ss.X().Fonts.Font[0], ss.X().Borders.Border[0] = nil, nil
+	cell = sheet.Cell("A2")
+	cell.SetStyleIndex(0)
+	ModifyCellStyle(ss, cell, func(d *StyleDelta) {
+		d.SetBold(true)
+		d.SetBorder(BorderAll, 1, Color{})
+	})
+	ss.X().CellXfs.Xf[0] = nil
+	cell.SetStyleIndex(0)
+	ModifyCellStyle(ss, cell, bold)
+	ss.X().CellXfs.Xf[0] = xf
+
+	// nothing to change: the default index, the cell is left as is
+	if idx := ModifyCellStyle(spreadsheet.StyleSheet{}, sheet.Cell("A3"), bold); idx != 0 {
+		t.Errorf("empty stylesheet: style %d, want 0", idx)
+	}
+	if sheet.Cell("A3").X().SAttr != nil {
+		t.Error("empty stylesheet: cell style is set")
+	}
+	if idx := ModifyCellStyle(ss, spreadsheet.Cell{}, bold); idx != 0 {
+		t.Errorf("empty cell: style %d, want 0", idx)
+	}
+	if _, err := ModifyCellStyleChecked(spreadsheet.StyleSheet{}, sheet.Cell("A3"), bold); err == nil {
+		t.Error("empty stylesheet: no error")
+	}
+}
+
+func TestModifyRangeStyleLines(t *testing.T) {
+	wb := spreadsheet.New()
+	sheet := wb.AddSheet()
+	ss := wb.StyleSheet
+	red := RGBColor(color.Red)
+	sheet.Cell("A2").SetString("a2")
+	sheet.AddMergedCells("A5", "B6")
+	// a column span to be split
+	cols := sheet.Column(1).X()
+	cols.MaxAttr, cols.WidthAttr = 4, new(float64)
+	*cols.WidthAttr = 20
+
+	if err := ModifyRangeStyle(ss, sheet, "2:3", func(d *StyleDelta) { d.SetBold(true) }); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(sheet.Row(2).Cells()); n != 1 {
+		t.Errorf("row 2 has %d cells, want 1", n)
+	}
+	for _, ref := range []string{"A2", "Z3"} {
+		es, err := GetEffectiveStyle(ss, sheet, ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !es.Font.Bold {
+			t.Errorf("%s isn't bold", ref)
+		}
+	}
+
+	if err := FillColorRange(ss, sheet, "$B:$C", RGBColor(color.Red)); err != nil {
+		t.Fatal(err)
+	}
+	var spans [][2]uint32
+	for _, c := range sheet.X().Cols[0].Col {
+		spans = append(spans, [2]uint32{c.MinAttr, c.MaxAttr})
+		if c.WidthAttr == nil || *c.WidthAttr != 20 {
+			t.Errorf("column %d-%d lost its width", c.MinAttr, c.MaxAttr)
+		}
+	}
+	if want := [][2]uint32{{1, 1}, {2, 2}, {3, 3}, {4, 4}}; fmt.Sprint(spans) != fmt.Sprint(want) {
+		t.Errorf("columns %v, want %v", spans, want)
+	}
+	tests := []struct {
+		ref    string
+		source string
+		bold   bool
+		fill   bool
+	}{
+		{"B100", StyleOfColumn, false, true},
+		{"D100", StyleOfDefault, false, false},
+		// the styled row gets cells of the columns
+		{"B2", StyleOfCell, true, true},
+		{"C3", StyleOfCell, true, true},
+		{"D3", StyleOfRow, true, false},
+		// the merged area is filled whole
+		{"A5", StyleOfCell, false, true},
+		{"A6", StyleOfCell, false, true},
+	}
+	for _, tt := range tests {
+		es, err := GetEffectiveStyle(ss, sheet, tt.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if es.Source != tt.source || es.Font.Bold != tt.bold || (es.Fill.FgColor == red) != tt.fill {
+			t.Errorf("%s: style of %s, bold %v, fill %v, want %s, %v, %v", tt.ref, es.Source, es.Font.Bold, es.Fill.FgColor, tt.source, tt.bold, tt.fill)
+		}
+	}
+	if c := sheet.Row(2).X().C; len(c) != 3 || *c[0].RAttr != "A2" || *c[1].RAttr != "B2" {
+		t.Errorf("cells of row 2 aren't sorted")
+	}
+
+	for _, ref := range []string{"0:0", "1:1048577", "A:XFE", "5:B"} {
+		if err := FillColorRange(ss, sheet, ref, RGBColor(color.Red)); err == nil {
+			t.Errorf("range %q is accepted", ref)
+		}
+	}
+}
+
+// cellStyle - cell style of cell, its font and border
+func cellStyle(t *testing.T, ss spreadsheet.StyleSheet, cell spreadsheet.Cell) (*sml.CT_Xf, *sml.CT_Font, *sml.CT_Border) {
+	t.Helper()
+	if cell.X().SAttr == nil {
+		t.Fatalf("cell %s has no style", cell.Reference())
+	}
+	xf := ss.X().CellXfs.Xf[*cell.X().SAttr]
+	font, border := ss.X().Fonts.Font[0], ss.X().Borders.Border[0]
+	if xf.FontIdAttr != nil {
+		font = ss.X().Fonts.Font[*xf.FontIdAttr]
+	}
+	if xf.BorderIdAttr != nil {
+		border = ss.X().Borders.Border[*xf.BorderIdAttr]
+	}
+	return xf, font, border
+}
+
+func TestStyleDeltaSetters(t *testing.T) {
+	red := RGBColor(color.Red)
+	tests := []struct {
+		name  string
+		fn    func(d *StyleDelta)
+		check func(xf *sml.CT_Xf, font *sml.CT_Font, border *sml.CT_Border) bool
+	}{
+		{"bold", func(d *StyleDelta) { d.SetBold(true) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return len(f.B) == 1 && *xf.ApplyFontAttr }},
+		{"not bold", func(d *StyleDelta) { d.SetBold(true); d.SetBold(false) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return len(f.B) == 0 }},
+		{"italic", func(d *StyleDelta) { d.SetItalic(true) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return len(f.I) == 1 }},
+		{"font size", func(d *StyleDelta) { d.SetFontSize(14.5) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return f.Sz[0].ValAttr == 14.5 }},
+		{"font color", func(d *StyleDelta) { d.SetFontColor(ThemeColor(4, -0.25)) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return colorOf(f.Color[0]) == ThemeColor(4, -0.25)
+			}},
+		{"automatic font color", func(d *StyleDelta) { d.SetFontColor(red); d.SetFontColor(Color{}) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return f.Color == nil }},
+		{"font name", func(d *StyleDelta) { d.SetFontName("Arial") },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return f.Name[0].ValAttr == "Arial" }},
+		{"horizontal alignment", func(d *StyleDelta) { d.SetHorizontalAlignment(sml.ST_HorizontalAlignmentCenter) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return xf.Alignment.HorizontalAttr == sml.ST_HorizontalAlignmentCenter && *xf.ApplyAlignmentAttr
+			}},
+		{"vertical alignment", func(d *StyleDelta) { d.SetVerticalAlignment(sml.ST_VerticalAlignmentTop) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return xf.Alignment.VerticalAttr == sml.ST_VerticalAlignmentTop
+			}},
+		{"wrapped", func(d *StyleDelta) { d.SetWrapped(true) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return *xf.Alignment.WrapTextAttr }},
+		{"not wrapped", func(d *StyleDelta) { d.SetWrapped(true); d.SetWrapped(false) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return xf.Alignment.WrapTextAttr == nil }},
+		{"indent", func(d *StyleDelta) { d.SetIndent(2) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return *xf.Alignment.IndentAttr == 2 }},
+		{"no indent", func(d *StyleDelta) { d.SetIndent(2); d.SetIndent(0) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return xf.Alignment.IndentAttr == nil }},
+		{"rotation", func(d *StyleDelta) { d.SetRotation(255) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return *xf.Alignment.TextRotationAttr == 255
+			}},
+		{"no rotation", func(d *StyleDelta) { d.SetRotation(45); d.SetRotation(0) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return xf.Alignment.TextRotationAttr == nil
+			}},
+		{"border", func(d *StyleDelta) { d.SetBorder(BorderLeft|BorderBottom, sml.ST_BorderStyleThin, red) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return b.Left.StyleAttr == sml.ST_BorderStyleThin && colorOf(b.Left.Color) == red &&
+					b.Bottom.StyleAttr == sml.ST_BorderStyleThin && (b.Top == nil || b.Top.StyleAttr != sml.ST_BorderStyleThin) &&
+					*xf.ApplyBorderAttr
+			}},
+		{"no border", func(d *StyleDelta) {
+			d.SetBorder(BorderAll, sml.ST_BorderStyleThick, red)
+			d.SetBorder(BorderRight, sml.ST_BorderStyleNone, red)
+		}, func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+			return b.Right.StyleAttr == sml.ST_BorderStyleNone && b.Right.Color == nil && b.Left.StyleAttr == sml.ST_BorderStyleThick
+		}},
+		{"fill color", func(d *StyleDelta) { d.SetFillColor(red) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return *xf.ApplyFillAttr && *xf.FillIdAttr >= 2
+			}},
+		{"number format", func(d *StyleDelta) { d.SetNumberFormat("# ##0.000") },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return *xf.NumFmtIdAttr >= 164 && *xf.ApplyNumberFormatAttr
+			}},
+		{"locked", func(d *StyleDelta) { d.SetLocked(false) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool {
+				return !*xf.Protection.LockedAttr && *xf.ApplyProtectionAttr
+			}},
+		{"hidden", func(d *StyleDelta) { d.SetHidden(true) },
+			func(xf *sml.CT_Xf, f *sml.CT_Font, b *sml.CT_Border) bool { return *xf.Protection.HiddenAttr }},
+	}
+	for _, tt := range tests {
+		wb := spreadsheet.New()
+		sheet := wb.AddSheet()
+		ss := wb.StyleSheet
+		cell := sheet.Cell("A1")
+		// the attributes set before are kept
+		ModifyCellStyle(ss, cell, func(d *StyleDelta) { d.SetFontName("Calibri"); d.SetBold(true); d.SetLocked(true) })
+		if _, err := ModifyCellStyleChecked(ss, cell, tt.fn); err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		xf, font, border := cellStyle(t, ss, cell)
+		if !tt.check(xf, font, border) {
+			t.Errorf("%s: style isn't changed", tt.name)
+		}
+		if tt.name != "font name" && font.Name[0].ValAttr != "Calibri" || tt.name != "not bold" && len(font.B) != 1 ||
+			tt.name != "locked" && !*xf.Protection.LockedAttr {
+			t.Errorf("%s: other attributes are changed", tt.name)
+		}
+		// the default style isn't changed
+		def := ss.X().CellXfs.Xf[0]
+		if def.Alignment != nil || def.Protection != nil || len(ss.X().Fonts.Font[0].B) != 0 || ss.X().Borders.Border[0].Left != nil && ss.X().Borders.Border[0].Left.StyleAttr != sml.ST_BorderStyleUnset {
+			t.Errorf("%s: default style is changed", tt.name)
+		}
+	}
+}